@@ -8,8 +8,10 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"github.com/EVODelavega/asciify/colour"
 	"github.com/EVODelavega/asciify/convert"
 	"github.com/EVODelavega/asciify/scale"
 	"github.com/vladimirvivien/go4vl/device"
@@ -21,6 +23,13 @@ type Args struct {
 	Cam              string
 	X, Y             uint // input stream resolution
 	negative, invert bool
+	colour           bool
+	charset, lum     string
+	dither, mode     string
+	depth            string
+	threshold        float64
+	workers          int
+	adj              convert.Adjustments
 }
 
 func main() {
@@ -52,6 +61,17 @@ func main() {
 	flag.BoolVar(&args.invert, "i", true, "Invert image (mirror output)")
 	flag.UintVar(&args.X, "x", 640, "Input camera resolution (width/X)")
 	flag.UintVar(&args.Y, "y", 480, "Input camera resolution (height/Y)")
+	flag.StringVar(&args.charset, "charset", "", fmt.Sprintf("Characters to use: a preset (%s), a file containing the characters, or the characters themselves (dense to sparse). Defaults to standard, or all printable ASCII characters in shape mode", strings.Join(convert.CharsetNames(), ", ")))
+	flag.StringVar(&args.lum, "lum", convert.AverageLuminance.String(), lumDoc())
+	flag.StringVar(&args.dither, "dither", convert.NoDither.String(), fmt.Sprintf("Dithering to apply (%s)", strings.Join(ditherNames(), ", ")))
+	flag.StringVar(&args.mode, "mode", "ascii", "Render mode (ascii, braille, shape)")
+	flag.Float64Var(&args.threshold, "t", convert.DefaultThreshold, "Brightness threshold (0-1) for a braille dot to be set")
+	flag.IntVar(&args.workers, "workers", 0, "Number of rows converted concurrently (0 means one per CPU)")
+	flag.BoolVar(&args.colour, "C", false, "Show image in colour (coloured characters)")
+	flag.StringVar(&args.depth, "depth", colour.AutoDepth, "Colour depth (auto, true, 256, 16, none). auto detects what the terminal supports")
+	flag.Float64Var(&args.adj.Gamma, "gamma", 1.0, "Gamma correction applied before picking characters (> 1 brightens mid-tones)")
+	flag.Float64Var(&args.adj.Brightness, "brightness", 0, "Brightness adjustment (-1 to 1)")
+	flag.Float64Var(&args.adj.Contrast, "contrast", 1.0, "Contrast adjustment (1 is unchanged, higher values increase contrast)")
 	// cmd := exec.Command("clear")
 	// cmd.Stdout = os.Stdout
 	flag.Parse()
@@ -59,6 +79,68 @@ func main() {
 	if args.Width != 0 && args.Height != 0 {
 		args.Factor = 0
 	}
+	render := convert.ImgToASCII
+	switch args.mode {
+	case "ascii":
+		if args.colour {
+			render = convert.ImgToASCIIColoured
+		}
+	case "braille":
+		render = convert.ImgToBraille
+		if args.colour {
+			render = convert.ImgToBrailleColoured
+		}
+		// width and height are the number of characters, each braille character is 2x4 pixels
+		args.Width *= 2
+		args.Height *= 4
+	case "shape":
+		if args.colour {
+			fmt.Println("colour (-C) isn't supported in shape mode")
+			os.Exit(1)
+		}
+		render = convert.ImgToShape
+		args.Width *= uint(convert.DefaultShapeCell.X)
+		args.Height *= uint(convert.DefaultShapeCell.Y)
+	default:
+		fmt.Printf("unsupported render mode %s\n", args.mode)
+		os.Exit(1)
+	}
+	var cs *convert.Charset
+	if args.charset != "" {
+		c, err := convert.ParseCharset(args.charset)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		cs = c
+	}
+	lum, err := convert.ParseLuminance(args.lum)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	dither, err := convert.ParseDither(args.dither)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	depth, err := colour.ResolveDepth(args.depth, os.Stdout)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	opts := convert.ConvertOpts{
+		Adjustments: args.adj,
+		Charset:     cs,
+		Luminance:   lum,
+		Dither:      dither,
+		Threshold:   args.threshold,
+		Placement:   convert.ForegroundPlacement,
+		Depth:       depth,
+		Workers:     args.workers,
+		Negative:    args.negative,
+		Invert:      args.invert,
+	}
 	camera, err := device.Open(
 		args.Cam,
 		device.WithPixFormat(v4l2.PixFormat{
@@ -81,13 +163,29 @@ func main() {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		ASCIIStr := convert.ImgToASCII(img, args.negative, args.invert)
+		ASCIIStr := render(img, opts)
 		clear()
 		fmt.Printf("\n%s\n", ASCIIStr)
 	}
 	<-done
 }
 
+func lumDoc() string {
+	models := make([]string, 0, len(convert.LuminanceModels))
+	for _, l := range convert.LuminanceModels {
+		models = append(models, l.String())
+	}
+	return fmt.Sprintf("Luminance model used to determine brightness (%s)", strings.Join(models, ", "))
+}
+
+func ditherNames() []string {
+	names := make([]string, 0, len(convert.DitherModes))
+	for _, d := range convert.DitherModes {
+		names = append(names, d.String())
+	}
+	return names
+}
+
 func clear() {
 	cmd := exec.Command("clear")
 	cmd.Stdout = os.Stdout
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/EVODelavega/asciify/colour"
 	"github.com/EVODelavega/asciify/convert"
 	"github.com/EVODelavega/asciify/scale"
 )
@@ -24,17 +28,47 @@ type Config struct {
 	reverse    bool
 	saveScaled string
 	colour     bool
+	colourFG   bool
+	colourBoth bool
+	charset    string
+	lum        string
+	dither     string
+	mode       string
+	depth      string
+	crop       string
+	format     string
+	bg         string
+	quantise   uint
+	stats      bool
+	// frames are the extra input files (the arguments), delay is how long each of them is shown in GIF output
+	frames []string
+	delay  time.Duration
+
+	// the flags for the conversion itself are parsed into this
+	opts convert.ConvertOpts
 
 	// not flags, but avoid doing the getting extensions a second time
 	inExt, outExt string
 }
 
+// stdio is the file name for stdin (-f) and stdout (-o)
+const stdio = "-"
+
 var (
 	ErrInvalidDimensions    = errors.New("need valid width/height or factor")
 	ErrInvalidInputFormat   = errors.New("unsupported input type")
 	ErrMissingInputFile     = errors.New("input file not specified or missing")
 	ErrInvalidScalingMethod = errors.New("specified scaling mode not supported")
 	ErrOutputFileExists     = errors.New("output file already exists")
+	ErrInvalidMode          = errors.New("specified render mode not supported")
+	ErrColourFlags          = errors.New("only one of -C, -Cf and -Cfb can be used")
+	ErrColourMode           = errors.New("colour (-C, -Cf and -Cfb) isn't supported in shape and edge mode")
+	ErrInvalidQuantise      = errors.New("quantise value must be between 0 and 255")
+	ErrFramesNotGIF         = errors.New("multiple input files are only supported for GIF output")
+
+	// render modes, ascii maps each pixel onto a character, braille renders 2x4 pixels per character
+	// shape matches the shape of characters to blocks of pixels, edge draws the outlines using -|/\
+	renderModes = []string{"ascii", "braille", "shape", "edge"}
 
 	// flag values map onto constants
 	scaleModes = map[string]scale.Mode{
@@ -62,6 +96,27 @@ func scaleModeFlagStr(s scale.Mode) string {
 	return ""
 }
 
+// colourPlacement sets the placement based on the -C flags, -Cf and -Cfb imply colour
+func (c *Config) colourPlacement() error {
+	n := 0
+	for _, f := range []bool{c.colour, c.colourFG, c.colourBoth} {
+		if f {
+			n++
+		}
+	}
+	if n > 1 {
+		return ErrColourFlags
+	}
+	switch {
+	case c.colourFG:
+		c.opts.Placement = convert.ForegroundPlacement
+	case c.colourBoth:
+		c.opts.Placement = convert.BothPlacement
+	}
+	c.colour = n == 1
+	return nil
+}
+
 // Validate makes sure the config makes sense - mode is handled in main function though
 func (c *Config) Validate() error {
 	if c.Width == 0 && c.Height == 0 {
@@ -79,18 +134,106 @@ func (c *Config) Validate() error {
 	} else {
 		c.Width, c.Height = 0, 0
 	}
-	if c.in == "" || !fileExists(c.in) {
-		return ErrMissingInputFile
+	if !validMode(c.mode) {
+		return ErrInvalidMode
 	}
-	ext, ok := scale.IsSupportedFile(c.in)
-	if !ok {
-		return ErrInvalidInputFormat
+	if err := c.colourPlacement(); err != nil {
+		return err
+	}
+	if c.colour && (c.mode == "shape" || c.mode == "edge") {
+		return ErrColourMode
+	}
+	if c.quantise > 255 {
+		return ErrInvalidQuantise
+	}
+	c.opts.Quantise = uint8(c.quantise)
+	// width and height are the number of characters, scale so each character gets the pixels it shows
+	switch c.mode {
+	case "braille":
+		c.Width *= 2
+		c.Height *= 4
+	case "shape":
+		c.Width *= uint(convert.DefaultShapeCell.X)
+		c.Height *= uint(convert.DefaultShapeCell.Y)
+	}
+	if c.crop != "" {
+		r, err := scale.ParseRect(c.crop)
+		if err != nil {
+			return err
+		}
+		c.Crop = r
+	}
+	// stdin can only be read once, if it's not an image, decoding it returns an error
+	if c.in != stdio {
+		if c.in == "" || !fileExists(c.in) {
+			return ErrMissingInputFile
+		}
+		ext, ok := scale.IsSupportedFile(c.in)
+		if !ok {
+			return ErrInvalidInputFormat
+		}
+		c.inExt = ext
+	}
+	if c.charset != "" {
+		cs, err := convert.ParseCharset(c.charset)
+		if err != nil {
+			return err
+		}
+		c.opts.Charset = cs
+	}
+	bg, err := convert.ParseBackground(c.bg)
+	if err != nil {
+		return err
+	}
+	c.opts.Background = bg
+	lum, err := convert.ParseLuminance(c.lum)
+	if err != nil {
+		return err
+	}
+	dither, err := convert.ParseDither(c.dither)
+	if err != nil {
+		return err
+	}
+	if c.depth != colour.AutoDepth {
+		if _, err := colour.ParseDepth(c.depth); err != nil {
+			return err
+		}
+	}
+	c.opts.Dither = dither
+	c.opts.Luminance = lum
+	c.opts.Negative = c.reverse
+	switch {
+	case c.format != "":
+		format, err := convert.ParseFormat(c.format)
+		if err != nil {
+			return err
+		}
+		c.opts.Format = format
+	case c.out != "":
+		// ANSI unless the extension says otherwise
+		c.opts.Format, _ = convert.FormatFromFile(c.out)
+	}
+	if len(c.frames) > 0 && c.opts.Format != convert.GIFFormat {
+		return ErrFramesNotGIF
+	}
+	for _, f := range c.frames {
+		if !fileExists(f) {
+			return ErrMissingInputFile
+		}
+		if _, ok := scale.IsSupportedFile(f); !ok {
+			return ErrInvalidInputFormat
+		}
 	}
-	c.inExt = ext
 	if c.out == "" {
 		c.out = "output.txt"
+		if c.opts.Format != convert.ANSIFormat {
+			c.out = "output." + c.opts.Format.String()
+		}
 	}
-	if !c.overwrite && fileExists(c.out) {
+	if c.out == stdio {
+		// the output already goes to stdout
+		c.printASCII = false
+	} else if !c.overwrite && fileExists(c.out) {
 		return ErrOutputFileExists
 	}
 	if len(c.saveScaled) > 0 {
@@ -112,17 +255,40 @@ func main() {
 	flag.UintVar(&conf.Width, "w", 0, "The width to resize the image to")
 	flag.UintVar(&conf.Height, "h", 0, "The height to resize the image to")
 	flag.Float64Var(&conf.Factor, "s", 1.0, "The scaling factor to use instead of width/height float value")
-	flag.StringVar(&conf.in, "f", "", "Input file")
-	flag.StringVar(&conf.out, "o", "", "Output file - default is output.txt")
+	flag.StringVar(&conf.in, "f", "", "Input file, - reads the image from stdin")
+	flag.StringVar(&conf.bg, "bg", convert.TerminalBackground, "Background colour partially transparent pixels are blended with: a hex colour (#1e1e1e or #fff), or terminal (or none) to keep them transparent")
+	flag.StringVar(&conf.crop, "crop", "", "Crop the image before scaling: x,y,width,height in pixels of the original image")
+	flag.BoolVar(&conf.IgnoreOrientation, "noexif", false, "Ignore the EXIF orientation of JPEG images, rather than rotating/flipping them the right way up")
+	flag.StringVar(&conf.out, "o", "", "Output file - default is output.txt, - writes to stdout")
+	flag.DurationVar(&conf.delay, "delay", 100*time.Millisecond, "How long each image is shown when turning a sequence of images (-f, and the files after the flags) into a GIF")
+	flag.StringVar(&conf.format, "format", "", fmt.Sprintf("Output format (%s), defaults to the format matching the output file extension, or ansi", strings.Join(formatNames(), ", ")))
+	flag.BoolVar(&conf.opts.Standalone, "standalone", false, "Write a complete HTML page, rather than just the <pre> block")
 	flag.StringVar(&scaleFlag, "m", scaleFlag, scaleDoc)
 	flag.BoolVar(&conf.overwrite, "r", false, "ReplaceAll output file if exists")
 	flag.BoolVar(&conf.printASCII, "A", false, "Print image as ASCII chars")
 	flag.BoolVar(&conf.reverse, "n", false, "Make negative of the ASCII output (white <> black)")
-	flag.BoolVar(&conf.colour, "C", false, "Show image in colour")
+	flag.BoolVar(&conf.colour, "C", false, "Show image in colour (background colour)")
+	flag.BoolVar(&conf.colourFG, "Cf", false, "Show image in colour (coloured characters)")
+	flag.BoolVar(&conf.colourBoth, "Cfb", false, "Show image in colour (coloured characters on a contrasting background)")
 	flag.StringVar(&conf.saveScaled, "c", "", "Save a copy of the scaled image under given file name")
+	flag.StringVar(&conf.depth, "depth", colour.AutoDepth, fmt.Sprintf("Colour depth (%s, %s). auto detects what the terminal supports, and writes the output file without colour", colour.AutoDepth, strings.Join(depthNames(), ", ")))
+	flag.UintVar(&conf.quantise, "q", 0, "Merge near-identical colours by rounding each channel to a multiple of this value, makes the output smaller (0 to disable)")
+	flag.IntVar(&conf.opts.Workers, "workers", 0, "Number of rows converted concurrently (0 means one per CPU)")
+	flag.BoolVar(&conf.stats, "stats", false, "Print the size of the coloured output, and how much was saved by only setting the colour when it changes")
+	flag.StringVar(&conf.charset, "charset", "", fmt.Sprintf("Characters to use: a preset (%s), a file containing the characters, or the characters themselves (dense to sparse). Defaults to standard, or all printable ASCII characters in shape mode", strings.Join(convert.CharsetNames(), ", ")))
+	flag.StringVar(&conf.lum, "lum", convert.AverageLuminance.String(), lumDoc())
+	flag.StringVar(&conf.dither, "dither", convert.NoDither.String(), fmt.Sprintf("Dithering to apply (%s)", strings.Join(ditherNames(), ", ")))
+	flag.StringVar(&conf.mode, "mode", renderModes[0], fmt.Sprintf("Render mode (%s)", strings.Join(renderModes, ", ")))
+	flag.Float64Var(&conf.opts.Threshold, "t", convert.DefaultThreshold, "Brightness threshold (0-1) for a braille dot to be set")
+	flag.Float64Var(&conf.opts.EdgeThreshold, "et", convert.DefaultEdgeThreshold, "Edge strength (0-1) required for a pixel to be drawn as an edge in edge mode")
+	flag.BoolVar(&conf.opts.EdgeFill, "fill", false, "Fill the areas between edges using the charset in edge mode")
+	flag.Float64Var(&conf.opts.Gamma, "gamma", 1.0, "Gamma correction applied before picking characters (> 1 brightens mid-tones)")
+	flag.Float64Var(&conf.opts.Brightness, "brightness", 0, "Brightness adjustment (-1 to 1)")
+	flag.Float64Var(&conf.opts.Contrast, "contrast", 1.0, "Contrast adjustment (1 is unchanged, higher values increase contrast)")
 
 	// get the args
 	flag.Parse()
+	conf.frames = flag.Args()
 	smode, err := scaleModeFromFalgStr(scaleFlag)
 	if err != nil {
 		fmt.Println(err)
@@ -134,20 +300,27 @@ func main() {
 		os.Exit(1)
 	}
 	// valid options, let's get started:
-	scaled, err := scale.File(conf.in, conf.ScaleOpts)
+	anim, err := loadImage(conf)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	var strImg string
-	// create scaled image string
-	if conf.colour {
-		strImg = convert.ImgToASCIIColoured(scaled, conf.reverse, false)
-	} else {
-		strImg = convert.ImgToASCII(scaled, conf.reverse, false)
+	// only GIF output has all frames, everything else uses the first one
+	scaled := anim.Frames[0].Image
+	// a file isn't a terminal, so unless the depth was set (or FORCE_COLOR is), the file won't have colours
+	// other formats (HTML) aren't meant for the terminal, so they get all the colours
+	var outFile *os.File
+	if conf.out == stdio {
+		outFile = os.Stdout
+	}
+	fileDepth, _ := colour.ResolveDepth(conf.depth, outFile)
+	if conf.depth == colour.AutoDepth && conf.opts.Format != convert.ANSIFormat {
+		fileDepth = colour.TrueColour
 	}
-	// first, write the scaled copy
-	if err := writeOut(conf, strImg); err != nil {
+	printDepth, _ := colour.ResolveDepth(conf.depth, os.Stdout)
+	// if the output looks the same, write to the file and stdout in one go
+	shared := conf.printASCII && conf.opts.Format == convert.ANSIFormat && (!conf.colour || printDepth == fileDepth)
+	if err := writeOut(conf, anim, fileDepth, shared); err != nil {
 		fmt.Println(err)
 	}
 	if len(conf.saveScaled) > 0 {
@@ -155,13 +328,128 @@ func main() {
 			fmt.Println(err)
 		}
 	}
-	if !conf.printASCII {
-		return
+	if conf.printASCII && !shared {
+		// whatever the file format, the terminal gets ANSI
+		if err := render(conf, scaled, os.Stdout, printDepth, convert.ANSIFormat); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// render writes the image to w using the given colour depth and format, rows are written as soon as they're done
+func render(c Config, scaled image.Image, w io.Writer, depth colour.Depth, format convert.Format) error {
+	opts := c.opts
+	opts.Depth = depth
+	opts.Format = format
+	stats := convert.EncodeStats{}
+	opts.Stats = &stats
+	err := renderFunc(c)(convert.NewRenderer(w, opts), scaled)
+	if c.stats && format == convert.ANSIFormat {
+		// stderr, so it doesn't end up in the output if that's redirected
+		fmt.Fprintf(os.Stderr, "depth %s: %s\n", depth, stats)
+	}
+	return err
+}
+
+func lumDoc() string {
+	models := make([]string, 0, len(convert.LuminanceModels))
+	for _, l := range convert.LuminanceModels {
+		models = append(models, l.String())
+	}
+	return fmt.Sprintf("Luminance model used to determine brightness (%s)", strings.Join(models, ", "))
+}
+
+func ditherNames() []string {
+	names := make([]string, 0, len(convert.DitherModes))
+	for _, d := range convert.DitherModes {
+		names = append(names, d.String())
+	}
+	return names
+}
+
+func formatNames() []string {
+	names := make([]string, 0, len(convert.Formats))
+	for _, f := range convert.Formats {
+		names = append(names, f.String())
 	}
-	fmt.Println(strImg)
+	return names
 }
 
-func writeOut(c Config, ascii string) error {
+func depthNames() []string {
+	names := make([]string, 0, len(colour.Depths))
+	for _, d := range colour.Depths {
+		names = append(names, d.String())
+	}
+	return names
+}
+
+// renderFunc returns the Renderer method for the mode
+func renderFunc(c Config) func(*convert.Renderer, image.Image) error {
+	switch {
+	case c.mode == "braille" && c.colour:
+		return (*convert.Renderer).BrailleColoured
+	case c.mode == "braille":
+		return (*convert.Renderer).Braille
+	case c.mode == "shape":
+		return (*convert.Renderer).Shape
+	case c.mode == "edge":
+		return (*convert.Renderer).Edges
+	case c.colour:
+		return (*convert.Renderer).ASCIIColoured
+	}
+	return (*convert.Renderer).ASCII
+}
+
+// loadImage reads and scales the input, followed by the extra files (if any), each of those is a frame. For GIF
+// output, all frames of a GIF are used
+func loadImage(c Config) (*scale.Animation, error) {
+	anim, err := loadFile(c, c.in)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range c.frames {
+		a, err := loadFile(c, f)
+		if err != nil {
+			return nil, err
+		}
+		anim.Frames = append(anim.Frames, a.Frames...)
+	}
+	return anim, nil
+}
+
+// loadFile reads and scales an image, from stdin if the path is -
+func loadFile(c Config, path string) (*scale.Animation, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path == stdio {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.Format == convert.GIFFormat {
+		if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && format == "gif" {
+			return scale.GIF(bytes.NewReader(data), c.ScaleOpts)
+		}
+	}
+	img, err := scale.Reader(bytes.NewReader(data), c.ScaleOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &scale.Animation{
+		Frames: []scale.Frame{{Image: img, Delay: c.delay}},
+	}, nil
+}
+
+// writeOut renders the image to the output file, and to stdout as well if stdout is set
+func writeOut(c Config, anim *scale.Animation, depth colour.Depth, stdout bool) error {
+	if c.out == stdio {
+		return renderOut(c, anim, os.Stdout, depth)
+	}
 	if c.overwrite && fileExists(c.out) {
 		os.Remove(c.out)
 	}
@@ -169,9 +457,22 @@ func writeOut(c Config, ascii string) error {
 	if err != nil {
 		return err
 	}
-	_, err = output.WriteString(ascii)
-	output.Close()
-	return err
+	defer output.Close()
+	var w io.Writer = output
+	if stdout {
+		w = io.MultiWriter(output, os.Stdout)
+	}
+	return renderOut(c, anim, w, depth)
+}
+
+// renderOut renders the output file: all frames for GIF output, the first frame for all other formats
+func renderOut(c Config, anim *scale.Animation, w io.Writer, depth colour.Depth) error {
+	if c.opts.Format != convert.GIFFormat {
+		return render(c, anim.Frames[0].Image, w, depth, c.opts.Format)
+	}
+	opts := c.opts
+	opts.Depth = depth
+	return convert.NewRenderer(w, opts).GIF(anim, renderFunc(c))
 }
 
 func saveScaledImg(c Config, scaled image.Image) error {
@@ -193,6 +494,15 @@ func saveScaledImg(c Config, scaled image.Image) error {
 	return nil
 }
 
+func validMode(m string) bool {
+	for _, v := range renderModes {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
 func fileExists(path string) bool {
 	info, err := os.Stat(path)
 	if os.IsNotExist(err) {
@@ -1,20 +1,36 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"image"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
+	"github.com/EVODelavega/asciify/colour"
 	"github.com/EVODelavega/asciify/convert"
 	"github.com/EVODelavega/asciify/scale"
 )
 
+// stdio is the file name for stdin
+const stdio = "-"
+
 var (
 	ErrInvalidInputFormat   = errors.New("unsupported input type")
 	ErrMissingInputFile     = errors.New("input file not specified or missing")
 	ErrInvalidScalingMethod = errors.New("specified scaling mode not supported")
+	ErrInvalidMode          = errors.New("specified render mode not supported")
+	ErrInvalidQuantise      = errors.New("quantise value must be between 0 and 255")
+
+	// render modes, full uses (1 or 3) spaces per pixel, half uses half blocks (2 pixels per character)
+	// quadrant and sextant use 2x2 and 2x3 pixels per character, each in 2 colours
+	renderModes = []string{"full", "half", "quadrant", "sextant"}
 
 	// flag values map onto constants
 	scaleModes = map[string]scale.Mode{
@@ -30,6 +46,19 @@ type Conf struct {
 	scale.ScaleOpts
 	in    string
 	force bool
+	mode  string
+	depth string
+	crop  string
+	bg    string
+	adj   convert.Adjustments
+	// quantise is the Quantise value (0-255), stats prints the encoding stats
+	quantise uint
+	stats    bool
+	workers  int
+	// format is the name of the output format, parsed into output
+	format     string
+	output     convert.Format
+	standalone bool
 }
 
 func (c *Conf) validate() error {
@@ -40,6 +69,39 @@ func (c *Conf) validate() error {
 			c.Factor = 0
 		}
 	}
+	if !validMode(c.mode) {
+		return ErrInvalidMode
+	}
+	if c.quantise > 255 {
+		return ErrInvalidQuantise
+	}
+	// width and height are the number of characters, scale so each character gets the pixels it shows
+	switch c.mode {
+	case "half":
+		c.Height *= 2
+	case "quadrant":
+		c.Width *= 2
+		c.Height *= 2
+	case "sextant":
+		c.Width *= 2
+		c.Height *= 3
+	}
+	if c.crop != "" {
+		r, err := scale.ParseRect(c.crop)
+		if err != nil {
+			return err
+		}
+		c.Crop = r
+	}
+	output, err := convert.ParseFormat(c.format)
+	if err != nil {
+		return err
+	}
+	c.output = output
+	// stdin can only be read once, if it's not an image, decoding it returns an error
+	if c.in == stdio {
+		return nil
+	}
 	if c.in == "" || !fileExists(c.in) {
 		return ErrMissingInputFile
 	}
@@ -61,9 +123,22 @@ func main() {
 	flag.UintVar(&conf.Width, "w", 0, "Max width - scales image (if required) to fit max width. recalculates -s flag")
 	flag.UintVar(&conf.Height, "h", 0, "Max height - scales image (if required) to fit max height. recalculates -s flag")
 	flag.Float64Var(&conf.Factor, "s", 1.0, "The scaling factor to use instead of width/height float value")
-	flag.StringVar(&conf.in, "f", "", "Input file")
+	flag.StringVar(&conf.in, "f", "", "Input file, - reads the image from stdin")
+	flag.StringVar(&conf.bg, "bg", convert.TerminalBackground, "Background colour partially transparent pixels are blended with: a hex colour (#1e1e1e or #fff), or terminal (or none) to keep them transparent")
+	flag.StringVar(&conf.crop, "crop", "", "Crop the image before scaling: x,y,width,height in pixels of the original image")
+	flag.BoolVar(&conf.IgnoreOrientation, "noexif", false, "Ignore the EXIF orientation of JPEG images, rather than rotating/flipping them the right way up")
 	flag.StringVar(&scaleFlag, "m", scaleFlag, scaleDoc)
 	flag.BoolVar(&conf.force, "S", false, "Force width and height to be used as absolute ratio - Ignore s flag")
+	flag.StringVar(&conf.mode, "mode", renderModes[0], fmt.Sprintf("Render mode (%s)", strings.Join(renderModes, ", ")))
+	flag.StringVar(&conf.depth, "depth", colour.AutoDepth, fmt.Sprintf("Colour depth (%s, %s). auto detects what the terminal supports, redirected output keeps all colours", colour.AutoDepth, strings.Join(depthNames(), ", ")))
+	flag.UintVar(&conf.quantise, "q", 0, "Merge near-identical colours by rounding each channel to a multiple of this value, makes the output smaller (0 to disable)")
+	flag.IntVar(&conf.workers, "workers", 0, "Number of rows converted concurrently (0 means one per CPU)")
+	flag.StringVar(&conf.format, "format", convert.ANSIFormat.String(), fmt.Sprintf("Output format (%s), the output is always written to stdout", strings.Join(formatNames(), ", ")))
+	flag.BoolVar(&conf.standalone, "standalone", false, "Write a complete HTML page, rather than just the <pre> block")
+	flag.BoolVar(&conf.stats, "stats", false, "Print the size of the output, and how much was saved by only setting the colour when it changes")
+	flag.Float64Var(&conf.adj.Gamma, "gamma", 1.0, "Gamma correction applied to the colours (> 1 brightens mid-tones)")
+	flag.Float64Var(&conf.adj.Brightness, "brightness", 0, "Brightness adjustment (-1 to 1)")
+	flag.Float64Var(&conf.adj.Contrast, "contrast", 1.0, "Contrast adjustment (1 is unchanged, higher values increase contrast)")
 	flag.Parse()
 	if err := conf.validate(); err != nil {
 		fmt.Println(err)
@@ -75,13 +150,149 @@ func main() {
 		os.Exit(1)
 	}
 	conf.Mode = smode
-	scaled, err := scale.FileToWindow(conf.in, conf.ScaleOpts)
+	anim, err := loadImage(conf)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	depth, err := colour.ResolveDepth(conf.depth, os.Stdout)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	strImg := convert.ImgToPreview(scaled, conf.force)
-	fmt.Println(strImg)
+	// a preview without colour is blank, so when the output is redirected (file, pipe) it keeps all the colours,
+	// only NO_COLOR and FORCE_COLOR (or setting the depth) turn them off. FORCE_COLOR values Detect ignores are
+	// ignored here too
+	redirected := conf.depth == colour.AutoDepth && !colour.IsTerminal(os.Stdout)
+	if _, forced := colour.Forced(); redirected && os.Getenv("NO_COLOR") == "" && !forced {
+		depth = colour.TrueColour
+	}
+	// the other formats (HTML, SVG, images) aren't meant for the terminal, so they get all the colours
+	if conf.depth == colour.AutoDepth && conf.output != convert.ANSIFormat {
+		depth = colour.TrueColour
+	}
+	bg, err := convert.ParseBackground(conf.bg)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	opts := convert.ConvertOpts{
+		Adjustments: conf.adj,
+		Background:  bg,
+		Depth:       depth,
+		Quantise:    uint8(conf.quantise),
+		Workers:     conf.workers,
+		Format:      conf.output,
+		Standalone:  conf.standalone,
+	}
+	var stats convert.EncodeStats
+	if conf.stats {
+		opts.Stats = &stats
+	}
+	// animations are only played in a terminal, when the output is redirected, playing a GIF that loops forever
+	// would never end, so only the first frame is written. GIF output gets all frames
+	switch {
+	case conf.output == convert.GIFFormat:
+		err = convert.NewRenderer(os.Stdout, opts).GIF(anim, renderFunc(conf))
+	case len(anim.Frames) > 1 && conf.output == convert.ANSIFormat && colour.IsTerminal(os.Stdout):
+		err = play(conf, opts, anim)
+	default:
+		err = show(conf, opts, anim.Frames[0].Image)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if conf.stats && conf.output == convert.ANSIFormat {
+		// stderr, so the stats don't end up in the output when it's redirected
+		fmt.Fprintln(os.Stderr, stats)
+	}
+}
+
+// loadImage reads and scales the input image, from stdin if the input is -. GIFs can have multiple frames,
+// any other image is returned as a single frame
+func loadImage(c Conf) (*scale.Animation, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if c.in == stdio {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(c.in)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && format == "gif" {
+		return scale.GIFToWindow(bytes.NewReader(data), c.ScaleOpts)
+	}
+	img, err := scale.ReaderToWindow(bytes.NewReader(data), c.ScaleOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &scale.Animation{
+		Frames: []scale.Frame{{Image: img}},
+	}, nil
+}
+
+// show writes a still image to stdout, rows are written as soon as they're done
+func show(c Conf, opts convert.ConvertOpts, img image.Image) error {
+	return renderFunc(c)(convert.NewRenderer(os.Stdout, opts), img)
+}
+
+// renderFunc returns the Renderer method for the mode
+func renderFunc(c Conf) func(*convert.Renderer, image.Image) error {
+	switch c.mode {
+	case "half":
+		return (*convert.Renderer).HalfBlock
+	case "quadrant":
+		return (*convert.Renderer).Quadrant
+	case "sextant":
+		return (*convert.Renderer).Sextant
+	}
+	return func(r *convert.Renderer, img image.Image) error {
+		return r.Preview(img, c.force)
+	}
+}
+
+// play converts all frames up front, and plays them until the animation is done, or until Ctrl-C is pressed.
+// The stats (if requested) are the total for all frames
+func play(c Conf, opts convert.ConvertOpts, anim *scale.Animation) error {
+	total := opts.Stats
+	frames := make([]convert.Frame, 0, len(anim.Frames))
+	for _, f := range anim.Frames {
+		var stats convert.EncodeStats
+		if total != nil {
+			opts.Stats = &stats
+		}
+		frames = append(frames, convert.Frame{
+			Output: convertFrame(c, opts, f.Image),
+			Delay:  f.Delay,
+		})
+		if total != nil {
+			total.Bytes += stats.Bytes
+			total.Naive += stats.Naive
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := convert.Play(ctx, os.Stdout, frames, anim.LoopCount); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+func convertFrame(c Conf, opts convert.ConvertOpts, img image.Image) string {
+	switch c.mode {
+	case "half":
+		return convert.ImgToHalfBlock(img, opts)
+	case "quadrant":
+		return convert.ImgToQuadrant(img, opts)
+	case "sextant":
+		return convert.ImgToSextant(img, opts)
+	}
+	return convert.ImgToPreview(img, c.force, opts)
 }
 
 func scaleModeFromFalgStr(fs string) (scale.Mode, error) {
@@ -101,6 +312,31 @@ func scaleModeFlagStr(s scale.Mode) string {
 	return ""
 }
 
+func formatNames() []string {
+	names := make([]string, 0, len(convert.Formats))
+	for _, f := range convert.Formats {
+		names = append(names, f.String())
+	}
+	return names
+}
+
+func depthNames() []string {
+	names := make([]string, 0, len(colour.Depths))
+	for _, d := range colour.Depths {
+		names = append(names, d.String())
+	}
+	return names
+}
+
+func validMode(m string) bool {
+	for _, v := range renderModes {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
 func fileExists(path string) bool {
 	info, err := os.Stat(path)
 	if os.IsNotExist(err) {
@@ -0,0 +1,84 @@
+package colour
+
+import (
+	"os"
+	"strings"
+)
+
+// Detect works out what colour depth to use when writing to f (usually os.Stdout). The environment is checked
+// in this order:
+//   - FORCE_COLOR: 0 or false turns colour off, 1 or true means 16 colours, 2 is 256 and 3 is true colour
+//   - NO_COLOR: if set (and not empty), colour is turned off (https://no-color.org)
+//   - if f is not a terminal (file, pipe), there's no colour
+//   - TERM=dumb means no colour
+//   - COLORTERM=truecolor or 24bit means true colour, as does a TERM ending in "-direct"
+//   - a TERM containing "256color" means 256 colours, any other TERM value gets 16 colours
+func Detect(f *os.File) Depth {
+	if d, ok := Forced(); ok {
+		return d
+	}
+	if os.Getenv("NO_COLOR") != "" || !IsTerminal(f) {
+		return NoColour
+	}
+	term := strings.ToLower(os.Getenv("TERM"))
+	if term == "dumb" {
+		return NoColour
+	}
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return TrueColour
+	}
+	switch {
+	case strings.HasSuffix(term, "-direct"):
+		return TrueColour
+	case strings.Contains(term, "256color"):
+		return Colours256
+	case term != "":
+		return Colours16
+	}
+	return NoColour
+}
+
+// AutoDepth is the value to pass to ResolveDepth to have the depth detected
+const AutoDepth = "auto"
+
+// ResolveDepth is used to handle user input (flags): AutoDepth detects the depth for f, anything else
+// is parsed using ParseDepth. Passing a nil file is treated as writing to a file (not a terminal)
+func ResolveDepth(name string, f *os.File) (Depth, error) {
+	if name == AutoDepth {
+		return Detect(f), nil
+	}
+	return ParseDepth(name)
+}
+
+// IsTerminal returns true if f is a terminal (character device)
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Forced returns the depth set by FORCE_COLOR, false if it isn't set or the value isn't one Detect understands
+func Forced() (Depth, bool) {
+	return forced(os.Getenv("FORCE_COLOR"))
+}
+
+// forced returns the depth set by FORCE_COLOR, false if it isn't set (or the value doesn't make sense)
+func forced(v string) (Depth, bool) {
+	switch strings.ToLower(v) {
+	case "0", "false":
+		return NoColour, true
+	case "1", "true":
+		return Colours16, true
+	case "2":
+		return Colours256, true
+	case "3":
+		return TrueColour, true
+	}
+	return TrueColour, false
+}
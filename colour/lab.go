@@ -0,0 +1,111 @@
+package colour
+
+import "math"
+
+// Lab is a colour in the CIE L*a*b* colour space (D65 white point), which is designed so that the distance
+// between 2 colours roughly matches how different they look
+type Lab struct {
+	L, A, B float64
+}
+
+// Lab converts the (sRGB) colour to L*a*b*
+func (c Colour256) Lab() Lab {
+	r, g, b := linear(c.R), linear(c.G), linear(c.B)
+	// sRGB to XYZ, normalised by the D65 reference white
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / 0.95047
+	y := 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / 1.08883
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return Lab{
+		L: 116*fy - 16,
+		A: 500 * (fx - fy),
+		B: 200 * (fy - fz),
+	}
+}
+
+// DeltaE returns the CIEDE2000 colour difference between c and o
+func (c Colour256) DeltaE(o Colour256) float64 {
+	return c.Lab().DeltaE(o.Lab())
+}
+
+// DeltaE returns the CIEDE2000 colour difference. 0 means identical, differences below 1 aren't noticeable
+func (l Lab) DeltaE(o Lab) float64 {
+	const (
+		rad  = math.Pi / 180
+		pow7 = 6103515625.0 // 25^7
+	)
+	c1, c2 := math.Hypot(l.A, l.B), math.Hypot(o.A, o.B)
+	cMean := (c1 + c2) / 2
+	cm7 := math.Pow(cMean, 7)
+	g := 0.5 * (1 - math.Sqrt(cm7/(cm7+pow7)))
+	a1, a2 := l.A*(1+g), o.A*(1+g)
+	c1, c2 = math.Hypot(a1, l.B), math.Hypot(a2, o.B)
+	h1, h2 := hueAngle(a1, l.B), hueAngle(a2, o.B)
+
+	dL := o.L - l.L
+	dC := c2 - c1
+	var dh float64
+	if c1*c2 != 0 {
+		dh = h2 - h1
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(c1*c2) * math.Sin(dh/2*rad)
+
+	lMean := (l.L + o.L) / 2
+	cMean = (c1 + c2) / 2
+	hMean := h1 + h2
+	if c1*c2 != 0 {
+		if math.Abs(h1-h2) > 180 {
+			if hMean < 360 {
+				hMean += 360
+			} else {
+				hMean -= 360
+			}
+		}
+		hMean /= 2
+	}
+	t := 1 - 0.17*math.Cos((hMean-30)*rad) + 0.24*math.Cos(2*hMean*rad) +
+		0.32*math.Cos((3*hMean+6)*rad) - 0.20*math.Cos((4*hMean-63)*rad)
+	lm50 := (lMean - 50) * (lMean - 50)
+	sL := 1 + 0.015*lm50/math.Sqrt(20+lm50)
+	sC := 1 + 0.045*cMean
+	sH := 1 + 0.015*cMean*t
+	cm7 = math.Pow(cMean, 7)
+	rT := -2 * math.Sqrt(cm7/(cm7+pow7)) * math.Sin(60*math.Exp(-math.Pow((hMean-275)/25, 2))*rad)
+
+	l2, c, h := dL/sL, dC/sC, dH/sH
+	return math.Sqrt(l2*l2 + c*c + h*h + rT*c*h)
+}
+
+// linear decodes an sRGB channel value
+func linear(v uint8) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+func labF(t float64) float64 {
+	const delta = 6.0 / 29.0
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29.0
+}
+
+// hueAngle returns the hue in degrees (0-360)
+func hueAngle(a, b float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
@@ -0,0 +1,225 @@
+package colour
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync/atomic"
+)
+
+// cacheBits is the number of bits of each channel used to pick the slot of a colour in the nearest colour cache
+const cacheBits = 5
+
+// Depth the number of colours the output can use
+type Depth uint32
+
+const (
+	// TrueColour 24 bit colour (38;2;r;g;b), the default
+	TrueColour Depth = iota
+	// Colours256 the xterm 256 colour palette (38;5;n), works in tmux, screen, and most terminals
+	Colours256
+	// Colours16 the basic ANSI colours (30-37 and 90-97), works pretty much everywhere
+	Colours16
+	// NoColour monochrome, no escape codes at all
+	NoColour
+)
+
+// Palette is a set of colours a terminal can display, each with its escape code number
+type Palette struct {
+	entries []paletteEntry
+	// nearest caches the lookups, images tend to have a lot of pixels with the same colour. The slot is picked using
+	// the top cacheBits of each channel, and holds the colour (r, g, b) and the index of the entry + 1 (0 means the
+	// slot is empty). Similar colours share a slot, the last one looked up wins, so the cache size is fixed
+	nearest []uint32
+}
+
+type paletteEntry struct {
+	id  uint8
+	c   Colour256
+	lab Lab
+}
+
+// jsonColour is the format of the colours in 256-colors.json
+type jsonColour struct {
+	ID  uint8  `json:"colorId"`
+	Hex string `json:"hexString"`
+	RGB struct {
+		R uint8 `json:"r"`
+		G uint8 `json:"g"`
+		B uint8 `json:"b"`
+	} `json:"rgb"`
+}
+
+var (
+	//go:embed 256-colors.json
+	xtermJSON []byte
+
+	// Palette256 the xterm 256 colour palette. The first 16 colours are left out: most terminals let the user
+	// change them (themes), so we can't be sure what they'll look like
+	Palette256 *Palette
+	// Palette16 the 16 basic ANSI colours (standard xterm values)
+	Palette16 *Palette
+	// xterm all 256 colours, indexed by escape code number
+	xterm [256]Colour256
+
+	ErrInvalidDepth = errors.New("specified colour depth not supported")
+
+	depthStr = map[Depth]string{
+		TrueColour: "true",
+		Colours256: "256",
+		Colours16:  "16",
+		NoColour:   "none",
+	}
+
+	// Depths all supported colour depths, from most to least colours
+	Depths = []Depth{
+		TrueColour,
+		Colours256,
+		Colours16,
+		NoColour,
+	}
+)
+
+func init() {
+	var colours []jsonColour
+	if err := json.Unmarshal(xtermJSON, &colours); err != nil {
+		panic(err)
+	}
+	for _, c := range colours {
+		xterm[c.ID] = Colour256{R: c.RGB.R, G: c.RGB.G, B: c.RGB.B}
+	}
+	Palette16 = newPalette(xterm[:16], 0)
+	Palette256 = newPalette(xterm[16:], 16)
+}
+
+func newPalette(colours []Colour256, offset int) *Palette {
+	p := &Palette{
+		entries: make([]paletteEntry, 0, len(colours)),
+		nearest: make([]uint32, 1<<(cacheBits*3)),
+	}
+	for i, c := range colours {
+		p.entries = append(p.entries, paletteEntry{
+			id:  uint8(i + offset),
+			c:   c,
+			lab: c.Lab(),
+		})
+	}
+	return p
+}
+
+// ParseDepth returns the depth for a given name (as returned by String)
+func ParseDepth(name string) (Depth, error) {
+	for d, s := range depthStr {
+		if s == name {
+			return d, nil
+		}
+	}
+	return TrueColour, ErrInvalidDepth
+}
+
+// String returns the depth name
+func (d Depth) String() string {
+	s, ok := depthStr[d]
+	if !ok {
+		return ""
+	}
+	return s
+}
+
+// Nearest returns the escape code number and the colour of the palette entry that looks the most like c
+// (smallest CIEDE2000 difference)
+func (p *Palette) Nearest(c Colour256) (uint8, Colour256) {
+	rgb := uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+	slot := &p.nearest[cacheSlot(c)]
+	// the conversions run concurrently, so the slots are read and written atomically
+	if v := atomic.LoadUint32(slot); v&0xff != 0 && v>>8 == rgb {
+		e := p.entries[v&0xff-1]
+		return e.id, e.c
+	}
+	lab := c.Lab()
+	best, match := -1.0, 0
+	for i, e := range p.entries {
+		if d := lab.DeltaE(e.lab); best < 0 || d < best {
+			best, match = d, i
+		}
+	}
+	atomic.StoreUint32(slot, rgb<<8|uint32(match+1))
+	return p.entries[match].id, p.entries[match].c
+}
+
+// cacheSlot returns the slot of the colour in the nearest colour cache
+func cacheSlot(c Colour256) int {
+	const shift = 8 - cacheBits
+	return int(c.R>>shift)<<(cacheBits*2) | int(c.G>>shift)<<cacheBits | int(c.B>>shift)
+}
+
+// Indexed returns the colour for an escape code number of the xterm palette (38;5;n). 0-15 are the 16 basic
+// colours, using the standard xterm values
+func Indexed(n uint8) Colour256 {
+	return xterm[n]
+}
+
+// Nearest returns the escape code number and colour of the closest match in the palette
+func (c Colour256) Nearest(p *Palette) (uint8, Colour256) {
+	return p.Nearest(c)
+}
+
+// Esc256 returns the escape code for the closest colour in the 256 colour palette, background or foreground
+func (c Colour256) Esc256(fg bool) string {
+	return string(c.append256(nil, fg))
+}
+
+func (c Colour256) append256(b []byte, fg bool) []byte {
+	n, _ := Palette256.Nearest(c)
+	if fg {
+		b = append(b, "\033[38;5;"...)
+	} else {
+		b = append(b, "\033[48;5;"...)
+	}
+	b = strconv.AppendUint(b, uint64(n), 10)
+	return append(b, 'm')
+}
+
+// Esc16 returns the escape code for the closest of the 16 ANSI colours, background or foreground
+func (c Colour256) Esc16(fg bool) string {
+	return string(c.append16(nil, fg))
+}
+
+func (c Colour256) append16(b []byte, fg bool) []byte {
+	n, _ := Palette16.Nearest(c)
+	// 0-7 are 30-37/40-47, the bright colours 8-15 are 90-97/100-107
+	code := 40 + int(n)
+	if n >= 8 {
+		code = 100 + int(n) - 8
+	}
+	if fg {
+		code -= 10
+	}
+	b = append(b, "\033["...)
+	b = strconv.AppendInt(b, int64(code), 10)
+	return append(b, 'm')
+}
+
+// Esc returns the escape code for the given depth, fg selects the foreground colour rather than the background
+// NoColour returns an empty string
+func (c Colour256) Esc(d Depth, fg bool) string {
+	return string(c.AppendEsc(nil, d, fg))
+}
+
+// AppendEsc appends the escape code (see Esc) to b, and returns the extended slice. Writing the escape codes
+// straight into a buffer saves allocating a string for every colour change
+func (c Colour256) AppendEsc(b []byte, d Depth, fg bool) []byte {
+	switch d {
+	case NoColour:
+		return b
+	case Colours256:
+		return c.append256(b, fg)
+	case Colours16:
+		return c.append16(b, fg)
+	}
+	if fg {
+		return c.appendTrue(b, trueColourF)
+	}
+	return c.appendTrue(b, trueColourB)
+}
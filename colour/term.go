@@ -2,16 +2,19 @@
 package colour
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"strconv"
+	"strings"
 )
 
 const (
 	// ResetColour is the terminal code to reset/turn off colour output
 	ResetColour = "\033[0m"
 
-	trueColourF = "\033[38;2;%d;%d;%dm"
+	trueColourF = "\033[38;2;"
+	trueColourB = "\033[48;2;"
 )
 
 // Colour256 contains values for all three (RGB) channels (no alpha)
@@ -22,7 +25,11 @@ type Colour256 struct {
 
 // FromColor returns nil for transparent pixels
 func FromColor(c color.Color) *Colour256 {
-	r, g, b, a := c.RGBA()
+	return FromRGBA(c.RGBA())
+}
+
+// FromRGBA does the same as FromColor, using the values as returned by color.Color.RGBA (0 - 0xffff)
+func FromRGBA(r, g, b, a uint32) *Colour256 {
 	if a == 0 {
 		return nil
 	}
@@ -33,11 +40,25 @@ func FromColor(c color.Color) *Colour256 {
 	}
 }
 
-// FromHex returns a Colour256 from a given hex string
+// ErrInvalidHex is returned by FromHex if the string isn't a hex colour
+var ErrInvalidHex = errors.New("hex colour should be RRGGBB or RGB, optionally prefixed with # or 0x")
+
+// FromHex returns a Colour256 from a given hex string (RRGGBB), optionally prefixed with # or 0x (as returned by Hex).
+// The CSS shorthand RGB works too, each digit is repeated: fa0 is ffaa00
 func FromHex(hex string) (*Colour256, error) {
-	val, err := strconv.ParseUint(string(hex), 16, 32)
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) > 2 && (hex[:2] == "0x" || hex[:2] == "0X") {
+		hex = hex[2:]
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return nil, ErrInvalidHex
+	}
+	val, err := strconv.ParseUint(hex, 16, 24)
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidHex
 	}
 	return &Colour256{
 		R: uint8(val >> 16),
@@ -48,7 +69,45 @@ func FromHex(hex string) (*Colour256, error) {
 
 // TrueEsc returns true-colour escape code
 func (c Colour256) TrueEsc() string {
-	return fmt.Sprintf("\033[48;2;%d;%d;%dm", c.R, c.G, c.B)
+	return c.trueEsc(trueColourB)
+}
+
+// TrueFgEsc returns the true-colour escape code for the foreground (text) colour
+func (c Colour256) TrueFgEsc() string {
+	return c.trueEsc(trueColourF)
+}
+
+// trueEsc appends the channels to the prefix. This gets called for pretty much every pixel, strconv is a lot
+// faster than fmt.Sprintf
+func (c Colour256) trueEsc(prefix string) string {
+	return string(c.appendTrue(make([]byte, 0, len(prefix)+12), prefix))
+}
+
+func (c Colour256) appendTrue(b []byte, prefix string) []byte {
+	b = append(b, prefix...)
+	b = strconv.AppendUint(b, uint64(c.R), 10)
+	b = append(b, ';')
+	b = strconv.AppendUint(b, uint64(c.G), 10)
+	b = append(b, ';')
+	b = strconv.AppendUint(b, uint64(c.B), 10)
+	return append(b, 'm')
+}
+
+// Contrast returns a colour that stands out against c: a dark shade for light colours, a light tint for dark ones
+func (c Colour256) Contrast() Colour256 {
+	// integer version of the Rec.601 luma
+	if (299*uint(c.R)+587*uint(c.G)+114*uint(c.B))/1000 > 127 {
+		return Colour256{
+			R: c.R / 4,
+			G: c.G / 4,
+			B: c.B / 4,
+		}
+	}
+	return Colour256{
+		R: 255 - (255-c.R)/4,
+		G: 255 - (255-c.G)/4,
+		B: 255 - (255-c.B)/4,
+	}
 }
 
 // Hex returns 256 colour as a hex string
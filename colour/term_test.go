@@ -0,0 +1,54 @@
+package colour
+
+import "testing"
+
+func TestFromHex(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want Colour256
+		err  bool
+	}{
+		{hex: "#1e1e1e", want: Colour256{R: 0x1e, G: 0x1e, B: 0x1e}},
+		{hex: "0x1E1E1E", want: Colour256{R: 0x1e, G: 0x1e, B: 0x1e}},
+		{hex: "1e2f3a", want: Colour256{R: 0x1e, G: 0x2f, B: 0x3a}},
+		{hex: "#fff", want: Colour256{R: 0xff, G: 0xff, B: 0xff}},
+		{hex: "#fa0", want: Colour256{R: 0xff, G: 0xaa, B: 0x00}},
+		{hex: "0x0a0", want: Colour256{R: 0x00, G: 0xaa, B: 0x00}},
+		{hex: "", err: true},
+		{hex: "#", err: true},
+		{hex: "0x", err: true},
+		{hex: "#12345", err: true},
+		{hex: "#1234567", err: true},
+		{hex: "#ff", err: true},
+		{hex: "#ggg", err: true},
+		{hex: "#1e1e1g", err: true},
+		{hex: "#+1e1e1", err: true},
+	}
+	for _, tt := range tests {
+		got, err := FromHex(tt.hex)
+		if tt.err {
+			if err != ErrInvalidHex {
+				t.Errorf("%q: expected ErrInvalidHex, got %v (%v)", tt.hex, err, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.hex, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.hex, tt.want, *got)
+		}
+	}
+}
+
+func TestFromHexRoundTrip(t *testing.T) {
+	c := Colour256{R: 0x12, G: 0xab, B: 0xef}
+	got, err := FromHex(c.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != c {
+		t.Errorf("expected %v, got %v", c, *got)
+	}
+}
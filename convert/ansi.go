@@ -0,0 +1,281 @@
+package convert
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+const (
+	escChar = '\033'
+	// tabWidth is the distance between tab stops
+	tabWidth = 8
+)
+
+// ansiParser keeps track of the cursor and the current colours while parsing
+type ansiParser struct {
+	grid   Grid
+	x, y   int
+	fg, bg *colour.Colour256
+}
+
+// ParseANSI reads ANSI text (like the output of ImgToASCIIColoured), and returns the grid of cells it draws.
+// Supported escape codes are the colours (true colour, 256 and 16 colours, default and reset), and the cursor
+// movements (up, down, forward, back, next/previous line, column, and position). Anything else is skipped.
+// Rows are padded with spaces to make the grid rectangular, so it can be rendered again (see Renderer.Grid).
+// The only errors returned are read errors
+func ParseANSI(r io.Reader) (Grid, error) {
+	br := bufio.NewReader(r)
+	p := ansiParser{}
+	for {
+		c, _, err := br.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case escChar:
+			if err := p.escape(br); err != nil {
+				return nil, err
+			}
+		case '\n':
+			p.x, p.y = 0, p.y+1
+		case '\r':
+			p.x = 0
+		case '\t':
+			p.x = (p.x/tabWidth + 1) * tabWidth
+		default:
+			if c < ' ' {
+				// other control characters (bell and the like) don't draw anything
+				continue
+			}
+			p.put(c)
+		}
+	}
+	return p.pad(), nil
+}
+
+// escape handles an escape sequence, the escape character itself has been read
+func (p *ansiParser) escape(br *bufio.Reader) error {
+	c, _, err := br.ReadRune()
+	if err != nil {
+		return eofOK(err)
+	}
+	switch c {
+	case '[':
+		return p.csi(br)
+	case ']':
+		// operating system command (window title and such), ends with BEL or ESC \
+		for {
+			c, _, err := br.ReadRune()
+			if err != nil {
+				return eofOK(err)
+			}
+			if c == '\a' {
+				return nil
+			}
+			if c == escChar {
+				_, _, err := br.ReadRune()
+				return eofOK(err)
+			}
+		}
+	}
+	// any other escape sequence is ESC followed by a single character
+	return nil
+}
+
+// csi handles a control sequence: parameters, followed by the final byte determining what it does
+func (p *ansiParser) csi(br *bufio.Reader) error {
+	params := strings.Builder{}
+	for {
+		c, _, err := br.ReadRune()
+		if err != nil {
+			return eofOK(err)
+		}
+		if c >= 0x40 && c <= 0x7e {
+			p.control(c, params.String())
+			return nil
+		}
+		params.WriteRune(c)
+	}
+}
+
+// control applies the control sequence
+func (p *ansiParser) control(final rune, params string) {
+	args := csiArgs(params)
+	if final == 'm' {
+		p.sgr(args)
+		return
+	}
+	// cursor movements default to 1, and 0 means 1 as well
+	n := 1
+	if len(args) > 0 && args[0] > 0 {
+		n = args[0]
+	}
+	switch final {
+	case 'A':
+		p.y -= n
+	case 'B':
+		p.y += n
+	case 'C':
+		p.x += n
+	case 'D':
+		p.x -= n
+	case 'E':
+		p.x, p.y = 0, p.y+n
+	case 'F':
+		p.x, p.y = 0, p.y-n
+	case 'G':
+		p.x = n - 1
+	case 'H', 'f':
+		// row;column, both 1 based
+		col := 1
+		if len(args) > 1 && args[1] > 0 {
+			col = args[1]
+		}
+		p.x, p.y = col-1, n-1
+	}
+	if p.x < 0 {
+		p.x = 0
+	}
+	if p.y < 0 {
+		p.y = 0
+	}
+}
+
+// sgr sets the colours
+func (p *ansiParser) sgr(args []int) {
+	if len(args) == 0 {
+		// ESC[m is a reset
+		args = []int{0}
+	}
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == 0:
+			p.fg, p.bg = nil, nil
+		case a >= 30 && a <= 37:
+			p.fg = indexed(a - 30)
+		case a >= 90 && a <= 97:
+			p.fg = indexed(a - 90 + 8)
+		case a == 39:
+			p.fg = nil
+		case a >= 40 && a <= 47:
+			p.bg = indexed(a - 40)
+		case a >= 100 && a <= 107:
+			p.bg = indexed(a - 100 + 8)
+		case a == 49:
+			p.bg = nil
+		case a == 38 || a == 48:
+			c, n := extendedColour(args[i+1:])
+			i += n
+			if c == nil {
+				continue
+			}
+			if a == 38 {
+				p.fg = c
+			} else {
+				p.bg = c
+			}
+		}
+		// the rest (bold, underline, ...) doesn't affect the colours
+	}
+}
+
+// extendedColour returns the colour of a 38 or 48 code: 5;n or 2;r;g;b, and the number of arguments used.
+// If the arguments are invalid, nil is returned
+func extendedColour(args []int) (*colour.Colour256, int) {
+	if len(args) == 0 {
+		return nil, 0
+	}
+	switch args[0] {
+	case 5:
+		if len(args) < 2 {
+			return nil, len(args)
+		}
+		return indexed(args[1]), 2
+	case 2:
+		if len(args) < 4 {
+			return nil, len(args)
+		}
+		if args[1] > 255 || args[2] > 255 || args[3] > 255 {
+			return nil, 4
+		}
+		return &colour.Colour256{
+			R: uint8(args[1]),
+			G: uint8(args[2]),
+			B: uint8(args[3]),
+		}, 4
+	}
+	return nil, 1
+}
+
+// put writes a character at the cursor, and moves the cursor along
+func (p *ansiParser) put(c rune) {
+	for len(p.grid) <= p.y {
+		p.grid = append(p.grid, nil)
+	}
+	row := p.grid[p.y]
+	for len(row) <= p.x {
+		row = append(row, Cell{Char: ' '})
+	}
+	row[p.x] = Cell{
+		Char: c,
+		FG:   p.fg,
+		BG:   p.bg,
+	}
+	p.grid[p.y] = row
+	p.x++
+}
+
+// pad makes all rows as long as the longest one
+func (p *ansiParser) pad() Grid {
+	cols := 0
+	for _, row := range p.grid {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	for y, row := range p.grid {
+		for len(row) < cols {
+			row = append(row, Cell{Char: ' '})
+		}
+		p.grid[y] = row
+	}
+	return p.grid
+}
+
+// csiArgs splits the parameters of a control sequence, empty or invalid parameters are 0
+func csiArgs(params string) []int {
+	if params == "" {
+		return nil
+	}
+	parts := strings.Split(params, ";")
+	args := make([]int, 0, len(parts))
+	for _, s := range parts {
+		v, _ := strconv.Atoi(s)
+		args = append(args, v)
+	}
+	return args
+}
+
+// indexed returns the palette colour, nil if n isn't a valid palette index
+func indexed(n int) *colour.Colour256 {
+	if n < 0 || n > 255 {
+		return nil
+	}
+	c := colour.Indexed(uint8(n))
+	return &c
+}
+
+// eofOK turns io.EOF into nil, input ending in the middle of an escape sequence is just ignored
+func eofOK(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,193 @@
+package convert
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+// fill returns the grid of a layout, the same grid the ImgTo functions encode
+func fill(l layout) Grid {
+	g := l.alloc()
+	for y := range g {
+		l.fill(g[y], y)
+	}
+	return g
+}
+
+// depthColour returns the colour c ends up as when encoded using depth d
+func depthColour(c *colour.Colour256, d colour.Depth) *colour.Colour256 {
+	if c == nil {
+		return nil
+	}
+	var nc colour.Colour256
+	switch d {
+	case colour.Colours256:
+		_, nc = colour.Palette256.Nearest(*c)
+	case colour.Colours16:
+		_, nc = colour.Palette16.Nearest(*c)
+	default:
+		nc = *c
+	}
+	return &nc
+}
+
+func sameColour(a, b *colour.Colour256) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func TestParseANSIRoundTrip(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 48, 40))
+	testPattern(img)
+	layouts := map[string]func(image.Image, ConvertOpts) layout{
+		"ascii coloured": func(img image.Image, opts ConvertOpts) layout {
+			return asciiLayout(img, opts, true)
+		},
+		"ascii coloured, both": func(img image.Image, opts ConvertOpts) layout {
+			opts.Placement = BothPlacement
+			return asciiLayout(img, opts, true)
+		},
+		"preview": func(img image.Image, opts ConvertOpts) layout {
+			return previewLayout(img, false, opts)
+		},
+		"half": halfBlockLayout,
+		"quadrant": func(img image.Image, opts ConvertOpts) layout {
+			return blockLayout(img, opts, 2, quadrantRune)
+		},
+		"sextant": func(img image.Image, opts ConvertOpts) layout {
+			return blockLayout(img, opts, 3, sextantRune)
+		},
+	}
+	for _, d := range []colour.Depth{colour.TrueColour, colour.Colours256, colour.Colours16} {
+		for name, fn := range layouts {
+			want := fill(fn(img, ConvertOpts{Depth: d}))
+			out, _ := want.EncodeWith(EncodeOpts{Depth: d})
+			got, err := ParseANSI(strings.NewReader(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) || len(got[0]) != len(want[0]) {
+				t.Errorf("%s (%s): expected %dx%d cells, got %dx%d", name, d, len(want[0]), len(want), len(got[0]), len(got))
+				continue
+			}
+		rows:
+			for y, row := range want {
+				for x, c := range row {
+					p := got[y][x]
+					// the foreground colour of a space isn't written
+					if p.Char != c.Char || !sameColour(p.BG, depthColour(c.BG, d)) ||
+						(c.Char != ' ' && !sameColour(p.FG, depthColour(c.FG, d))) {
+						t.Errorf("%s (%s): cell %d, %d differs: expected %q %v %v, got %q %v %v", name, d, x, y,
+							c.Char, depthColour(c.FG, d), depthColour(c.BG, d), p.Char, p.FG, p.BG)
+						break rows
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseANSIMalformed(t *testing.T) {
+	red, blue := colour.Indexed(1), colour.Indexed(4)
+	tests := []struct {
+		name string
+		in   string
+		want []Cell
+	}{
+		{
+			name: "channel out of range",
+			in:   "\033[38;2;300;0;0mA",
+			want: []Cell{{Char: 'A'}},
+		},
+		{
+			name: "palette index out of range",
+			in:   "\033[48;5;256mA",
+			want: []Cell{{Char: 'A'}},
+		},
+		{
+			name: "missing palette index",
+			in:   "\033[31m\033[38;5mA",
+			want: []Cell{{Char: 'A', FG: &red}},
+		},
+		{
+			name: "missing channels",
+			in:   "\033[38;2;1;2mA",
+			want: []Cell{{Char: 'A'}},
+		},
+		{
+			name: "unknown colour type, the rest still applies",
+			in:   "\033[38;9;44mA",
+			want: []Cell{{Char: 'A', BG: &blue}},
+		},
+		{
+			name: "empty and invalid parameters are a reset",
+			in:   "\033[41mA\033[;31mB\033[44mC\033[<;34mD",
+			want: []Cell{{Char: 'A', BG: &red}, {Char: 'B', FG: &red}, {Char: 'C', FG: &red, BG: &blue}, {Char: 'D', FG: &blue}},
+		},
+		{
+			name: "attributes don't change the colour",
+			in:   "\033[1;4;31;7mA",
+			want: []Cell{{Char: 'A', FG: &red}},
+		},
+		{
+			name: "default colours",
+			in:   "\033[31;44mA\033[39mB\033[49mC",
+			want: []Cell{{Char: 'A', FG: &red, BG: &blue}, {Char: 'B', BG: &blue}, {Char: 'C'}},
+		},
+		{
+			name: "other control sequences are skipped",
+			in:   "\033[31mA\033[2KB\033]0;title\aC",
+			want: []Cell{{Char: 'A', FG: &red}, {Char: 'B', FG: &red}, {Char: 'C', FG: &red}},
+		},
+		{
+			name: "truncated escape sequence",
+			in:   "\033[31mA\033[38;2;1",
+			want: []Cell{{Char: 'A', FG: &red}},
+		},
+		{
+			name: "escape at the end",
+			in:   "A\033",
+			want: []Cell{{Char: 'A'}},
+		},
+		{
+			name: "only a truncated escape sequence",
+			in:   "\033[48;5;",
+		},
+	}
+	for _, tt := range tests {
+		g, err := ParseANSI(strings.NewReader(tt.in))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if len(tt.want) == 0 {
+			if len(g) != 0 {
+				t.Errorf("%s: expected an empty grid, got %d rows", tt.name, len(g))
+			}
+			continue
+		}
+		if len(g) != 1 || len(g[0]) != len(tt.want) {
+			t.Errorf("%s: expected a single row of %d cells, got %v", tt.name, len(tt.want), g)
+			continue
+		}
+		for x, c := range tt.want {
+			if p := g[0][x]; p.Char != c.Char || !sameColour(p.FG, c.FG) || !sameColour(p.BG, c.BG) {
+				t.Errorf("%s: cell %d: expected %q %v %v, got %q %v %v", tt.name, x, c.Char, c.FG, c.BG, p.Char, p.FG, p.BG)
+			}
+		}
+	}
+}
+
+func TestParseANSIReadError(t *testing.T) {
+	errRead := errors.New("read error")
+	if _, err := ParseANSI(iotest.ErrReader(errRead)); err != errRead {
+		t.Errorf("expected the read error, got %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package convert
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+// TerminalBackground is the background name to leave transparent pixels to the terminal, "none" works too
+const TerminalBackground = "terminal"
+
+var ErrInvalidBackground = errors.New("background should be a hex colour (#rrggbb or #rgb), terminal, or none")
+
+// ParseBackground returns the background colour for a hex colour (with or without # or 0x), nil for the terminal
+// background. The result can be used as ConvertOpts.Background
+func ParseBackground(s string) (*colour.Colour256, error) {
+	switch strings.ToLower(s) {
+	case "", TerminalBackground, "none":
+		return nil, nil
+	}
+	c, err := colour.FromHex(s)
+	if err != nil {
+		return nil, ErrInvalidBackground
+	}
+	return c, nil
+}
@@ -0,0 +1,203 @@
+package convert
+
+import (
+	"image"
+	"math/bits"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+// quadrants maps a 2x2 pattern onto the block element that shows it. Bits are set for the pixels shown in
+// the foreground colour: 1 top left, 2 top right, 4 bottom left, 8 bottom right
+var quadrants = [16]rune{
+	' ', '▘', '▝', '▀',
+	'▖', '▌', '▞', '▛',
+	'▗', '▚', '▐', '▜',
+	'▄', '▙', '▟', '█',
+}
+
+// blockPixel is a pixel in a cell, opaque is false for transparent pixels
+type blockPixel struct {
+	r, g, b float64
+	opaque  bool
+}
+
+// ImgToQuadrant renders each 2x2 block of pixels as a single quadrant block element (▖▗▘▝▚...). For every cell
+// the pattern and foreground/background colours are chosen so the difference with the original pixels is as
+// small as possible. Only the adjustments, Invert, Background and the colour options (depth, quantise, stats)
+// are used from opts
+func ImgToQuadrant(img image.Image, opts ConvertOpts) string {
+	return opts.render(blockLayout(img, opts, 2, quadrantRune))
+}
+
+// ImgToSextant does the same as ImgToQuadrant, but uses the sextant characters (2x3 blocks) from the
+// Symbols for Legacy Computing block. Not all fonts support these
+func ImgToSextant(img image.Image, opts ConvertOpts) string {
+	return opts.render(blockLayout(img, opts, 3, sextantRune))
+}
+
+func quadrantRune(mask int) rune {
+	return quadrants[mask]
+}
+
+// sextantRune returns the character for a 2x3 pattern. Bits are set from top left to bottom right, per row.
+// The sextants (U+1FB00 onward) follow that order, skipping the patterns that already exist as block elements
+func sextantRune(mask int) rune {
+	switch mask {
+	case 0:
+		return ' '
+	case 21: // left column
+		return '▌'
+	case 42: // right column
+		return '▐'
+	case 63:
+		return '█'
+	}
+	r := rune(0x1FB00 + mask - 1)
+	if mask > 21 {
+		r--
+	}
+	if mask > 42 {
+		r--
+	}
+	return r
+}
+
+// blockMask returns the pattern (as used by quadrantRune and sextantRune) and number of rows (2 or 3) for
+// a block element, false if r isn't one. Half blocks are returned as quadrants
+func blockMask(r rune) (int, int, bool) {
+	for mask, q := range quadrants {
+		if q == r {
+			return mask, 2, true
+		}
+	}
+	if r < 0x1FB00 || r > 0x1FB3B {
+		return 0, 0, false
+	}
+	// the reverse of sextantRune
+	mask := int(r-0x1FB00) + 1
+	if mask >= 21 {
+		mask++
+	}
+	if mask >= 42 {
+		mask++
+	}
+	return mask, 3, true
+}
+
+// blockLayout splits the image in cells 2 pixels wide and h pixels high
+func blockLayout(img image.Image, opts ConvertOpts, h int, glyph func(int) rune) layout {
+	px := newPixels(img, opts.Background)
+	size := px.size
+	return layout{
+		rows: (size.Y + h - 1) / h,
+		cols: (size.X + 1) / 2,
+		fill: func(row []Cell, y int) {
+			blockRow(row, px, y*h, h, opts, glyph)
+		},
+	}
+}
+
+func blockRow(row []Cell, px pixels, y, h int, opts ConvertOpts, glyph func(int) rune) {
+	size := px.size
+	pixels := make([]blockPixel, 2*h)
+	rc := newRowColours(2 * len(row))
+	for cx := range row {
+		for dy := 0; dy < h; dy++ {
+			for dx := 0; dx < 2; dx++ {
+				x := cx*2 + dx
+				if opts.Invert {
+					x = size.X - x - 1
+				}
+				var p blockPixel
+				if x >= 0 && x < size.X && y+dy < size.Y {
+					if c, ok := px.rgb(x, y+dy); ok {
+						c = opts.adjust(c)
+						p = blockPixel{r: float64(c.R), g: float64(c.G), b: float64(c.B), opaque: true}
+					}
+				}
+				pixels[dy*2+dx] = p
+			}
+		}
+		mask, fg, bg := fitCell(pixels, rc)
+		row[cx] = Cell{
+			Char: glyph(mask),
+			FG:   fg,
+			BG:   bg,
+		}
+	}
+}
+
+// fitCell picks the pattern and the 2 colours that best match the pixels. For a given split of the pixels
+// in 2 groups, the best colours are the averages of each group, and the squared error is the sum of squares
+// minus |sum|^2/n for both groups. We just try all splits (8 for quadrants, 32 for sextants)
+// If any of the pixels are transparent, we can't use a background colour, so the opaque pixels are
+// shown in the foreground using their average colour. The colours are added to rc
+func fitCell(pixels []blockPixel, rc *rowColours) (int, *colour.Colour256, *colour.Colour256) {
+	opaque := 0
+	var sum blockPixel
+	for i, p := range pixels {
+		if !p.opaque {
+			continue
+		}
+		opaque |= 1 << i
+		sum.add(p)
+	}
+	full := 1<<len(pixels) - 1
+	switch opaque {
+	case 0:
+		return 0, nil, nil
+	case full:
+	default:
+		return opaque, rc.add(sum.avg(bits.OnesCount(uint(opaque)))), nil
+	}
+	// no split, just use a background colour
+	best, bestMask := sum.score(len(pixels)), 0
+	// the highest bit is never set, swapping fg and bg gives us the same split
+	for mask := 1; mask < 1<<(len(pixels)-1); mask++ {
+		var fg blockPixel
+		for i, p := range pixels {
+			if mask&(1<<i) != 0 {
+				fg.add(p)
+			}
+		}
+		bg := blockPixel{r: sum.r - fg.r, g: sum.g - fg.g, b: sum.b - fg.b}
+		n := bits.OnesCount(uint(mask))
+		if s := fg.score(n) + bg.score(len(pixels)-n); s > best {
+			best, bestMask = s, mask
+		}
+	}
+	if bestMask == 0 {
+		return 0, nil, rc.add(sum.avg(len(pixels)))
+	}
+	var fg blockPixel
+	for i, p := range pixels {
+		if bestMask&(1<<i) != 0 {
+			fg.add(p)
+		}
+	}
+	n := bits.OnesCount(uint(bestMask))
+	bg := blockPixel{r: sum.r - fg.r, g: sum.g - fg.g, b: sum.b - fg.b}
+	return bestMask, rc.add(fg.avg(n)), rc.add(bg.avg(len(pixels) - n))
+}
+
+func (p *blockPixel) add(o blockPixel) {
+	p.r += o.r
+	p.g += o.g
+	p.b += o.b
+}
+
+// score is |sum|^2/n, the higher the score, the lower the error
+func (p blockPixel) score(n int) float64 {
+	return (p.r*p.r + p.g*p.g + p.b*p.b) / float64(n)
+}
+
+// avg treats p as the sum of n pixels and returns the average colour
+func (p blockPixel) avg(n int) colour.Colour256 {
+	f := float64(n)
+	return colour.Colour256{
+		R: uint8(p.r/f + 0.5),
+		G: uint8(p.g/f + 0.5),
+		B: uint8(p.b/f + 0.5),
+	}
+}
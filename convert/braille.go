@@ -0,0 +1,108 @@
+package convert
+
+import (
+	"image"
+	"math"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+const (
+	// brailleBase is the blank braille pattern (U+2800), the dots are set by adding the bits below
+	brailleBase = 0x2800
+	// DefaultThreshold is the brightness from which a dot is considered to be set
+	DefaultThreshold = 0.5
+)
+
+// brailleDots maps the position of a pixel within a 2x4 block onto the bit for the corresponding dot.
+// braille dots are numbered 1-2-3 down the left column, 4-5-6 down the right, then 7 and 8 at the bottom
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// ImgToBraille renders each 2x4 block of pixels as a single braille character, so the image is shown at 8 times
+// the resolution of ImgToASCII. A dot is set if the brightness is above the threshold (or below if Negative is set)
+// dithering is supported, Charset is ignored
+func ImgToBraille(img image.Image, opts ConvertOpts) string {
+	return opts.render(brailleLayout(img, opts, false))
+}
+
+// ImgToBrailleColoured does the same as ImgToBraille, but sets the foreground colour of each character to the
+// average colour of the dots that are set
+func ImgToBrailleColoured(img image.Image, opts ConvertOpts) string {
+	return opts.render(brailleLayout(img, opts, true))
+}
+
+func brailleLayout(img image.Image, opts ConvertOpts, coloured bool) layout {
+	px := newPixels(img, opts.Background)
+	size := px.size
+	t := opts.threshold()
+	levels := ditherGrid(px, thresholdQuantiser(t), opts)
+	return layout{
+		rows: (size.Y + 3) / 4,
+		cols: (size.X + 1) / 2,
+		fill: func(row []Cell, y int) {
+			brailleRow(row, px, levels, y*4, t, opts, coloured)
+		},
+	}
+}
+
+// brailleRow populates a row of cells using image lines y through y+3
+func brailleRow(row []Cell, px pixels, levels [][]float64, y int, t float64, opts ConvertOpts, coloured bool) {
+	size := px.size
+	var rc *rowColours
+	if coloured {
+		rc = newRowColours(len(row))
+	}
+	for cx := range row {
+		char := rune(brailleBase)
+		var r, g, b, n uint
+		for dy := 0; dy < 4 && y+dy < size.Y; dy++ {
+			for dx := 0; dx < 2; dx++ {
+				x := cx*2 + dx
+				if opts.Invert {
+					x = size.X - x - 1
+				}
+				if x < 0 || x >= size.X {
+					continue
+				}
+				v := levels[y+dy][x]
+				if math.IsNaN(v) || (v >= t) == opts.Negative {
+					continue
+				}
+				char += brailleDots[dy][dx]
+				if !coloured {
+					continue
+				}
+				if c, ok := px.rgb(x, y+dy); ok {
+					c = opts.adjust(c)
+					r, g, b = r+uint(c.R), g+uint(c.G), b+uint(c.B)
+					n++
+				}
+			}
+		}
+		if char == brailleBase {
+			row[cx] = Cell{Char: ' '}
+			continue
+		}
+		row[cx] = Cell{Char: char}
+		if n > 0 {
+			row[cx].FG = rc.add(colour.Colour256{
+				R: uint8(r / n),
+				G: uint8(g / n),
+				B: uint8(b / n),
+			})
+		}
+	}
+}
+
+// threshold returns the threshold to use, falls back to the default
+func (o ConvertOpts) threshold() float64 {
+	if o.Threshold <= 0 {
+		return DefaultThreshold
+	}
+	return o.Threshold
+}
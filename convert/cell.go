@@ -0,0 +1,95 @@
+package convert
+
+import (
+	"github.com/EVODelavega/asciify/colour"
+)
+
+// Cell is a single character of the output, with its foreground and background colour (nil means no colour)
+type Cell struct {
+	Char   rune
+	FG, BG *colour.Colour256
+}
+
+// Placement determines where the colour of a pixel goes in coloured ASCII output
+type Placement uint32
+
+const (
+	// BackgroundPlacement sets the background colour, the character uses the default terminal colour
+	BackgroundPlacement Placement = iota
+	// ForegroundPlacement colours the character itself, the background is left alone
+	ForegroundPlacement
+	// BothPlacement colours the character, and uses a contrasting background colour so the character stands out
+	BothPlacement
+)
+
+// Grid is the rendered image, Grid[y][x] is the character at position x on line y
+type Grid [][]Cell
+
+// String returns the grid as a string, with the true-colour escape codes
+func (g Grid) String() string {
+	return g.Encode(colour.TrueColour)
+}
+
+// Encode returns the grid as a string, with the escape codes for the given colour depth. See EncodeWith
+func (g Grid) Encode(d colour.Depth) string {
+	s, _ := g.EncodeWith(EncodeOpts{Depth: d})
+	return s
+}
+
+// Cell returns the cell for a character using the colour as per the placement (nil means no colour)
+func (p Placement) Cell(char rune, c *colour.Colour256) Cell {
+	return p.cell(char, c, nil)
+}
+
+// cell does the same as Cell, the contrasting background colour (if any) is added to rc
+func (p Placement) cell(char rune, c *colour.Colour256, rc *rowColours) Cell {
+	cell := Cell{Char: char}
+	if c == nil {
+		return cell
+	}
+	switch p {
+	case ForegroundPlacement:
+		cell.FG = c
+	case BothPlacement:
+		cell.FG, cell.BG = c, rc.add(c.Contrast())
+	default:
+		cell.BG = c
+	}
+	return cell
+}
+
+// rowColours holds the colours of the cells of a row. The cells point to a colour, allocating those one at a time
+// means an allocation for every pixel, this way there's one for every row
+type rowColours []colour.Colour256
+
+func newRowColours(n int) *rowColours {
+	rc := make(rowColours, 0, n)
+	return &rc
+}
+
+// add stores the colour and returns a pointer to it. If there's no more room, a new array is allocated, the colours
+// that are already used stay where they are. A nil rc allocates the colour by itself
+func (rc *rowColours) add(c colour.Colour256) *colour.Colour256 {
+	if rc == nil {
+		// a copy, so only this colour is allocated, rather than c every time
+		nc := c
+		return &nc
+	}
+	*rc = append(*rc, c)
+	return &(*rc)[len(*rc)-1]
+}
+
+// pixel adds the colour of the pixel, with the adjustments applied, nil if the pixel is transparent
+func (rc *rowColours) pixel(px pixels, adj Adjustments, x, y int) *colour.Colour256 {
+	r, g, b, a := px.RGBA(x, y)
+	return rc.rgba(adj, r, g, b, a)
+}
+
+// rgba does the same as pixel, for a pixel that's been read already
+func (rc *rowColours) rgba(adj Adjustments, r, g, b, a uint32) *colour.Colour256 {
+	c, ok := toColour(r, g, b, a)
+	if !ok {
+		return nil
+	}
+	return rc.add(adj.adjust(c))
+}
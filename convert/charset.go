@@ -0,0 +1,142 @@
+package convert
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Charset is a ramp of characters used to represent the brightness of a pixel. The characters are ordered
+// from dense (dark) to sparse (light), so the last character is usually a space
+type Charset struct {
+	name  string
+	chars []rune
+	// step is the range of brightness values (0-1) each character represents: 1 / len(chars)
+	step float64
+}
+
+var (
+	ErrCharsetTooShort = errors.New("charset needs at least 2 characters")
+
+	// DefaultCharset is the ramp we've always used
+	DefaultCharset = mustCharset("standard", string(ASCIIChars))
+
+	// presets maps the names we accept (e.g. for flags) onto the built-in charsets
+	presets = map[string]*Charset{
+		DefaultCharset.name: DefaultCharset,
+		"dense":             mustCharset("dense", "$@B%8&WM#*oahkbdpqwmZO0QLCJUYXzcvunxrjft/\\|()1{}[]?-_+~<>i!lI;:,\"^`'. "),
+		"blocks":            mustCharset("blocks", "█▓▒░ "),
+		"digits":            mustCharset("digits", "8096532471 "),
+	}
+)
+
+// NewCharset creates a charset from the given string. The characters should be ordered from dense to sparse
+func NewCharset(chars string) (*Charset, error) {
+	return newCharset("custom", chars)
+}
+
+// CharsetFromFile reads the ramp from a file. Trailing line breaks are ignored, everything else
+// (including trailing spaces) is considered part of the ramp
+func CharsetFromFile(path string) (*Charset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newCharset(path, strings.TrimRight(string(data), "\r\n"))
+}
+
+// GetCharset returns the built-in charset by name, false if there's no such preset
+func GetCharset(name string) (*Charset, bool) {
+	cs, ok := presets[name]
+	return cs, ok
+}
+
+// ParseCharset is used to handle user input (flags). The value can be the name of a preset, a path to a file
+// containing the ramp, or the characters to use
+func ParseCharset(val string) (*Charset, error) {
+	if cs, ok := GetCharset(val); ok {
+		return cs, nil
+	}
+	if info, err := os.Stat(val); err == nil && !info.IsDir() {
+		return CharsetFromFile(val)
+	}
+	return NewCharset(val)
+}
+
+// CharsetNames returns the names of all presets, sorted
+func CharsetNames() []string {
+	names := make([]string, 0, len(presets))
+	for k := range presets {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newCharset(name, chars string) (*Charset, error) {
+	runes := []rune(chars)
+	if len(runes) < 2 {
+		return nil, ErrCharsetTooShort
+	}
+	return &Charset{
+		name:  name,
+		chars: runes,
+		step:  1.0 / float64(len(runes)),
+	}, nil
+}
+
+func mustCharset(name, chars string) *Charset {
+	cs, err := newCharset(name, chars)
+	if err != nil {
+		panic(err)
+	}
+	return cs
+}
+
+// Rune returns the character for the given brightness (0-1). By default, lighter colours map onto
+// the start of the ramp, negative swaps that around
+func (c *Charset) Rune(v float64, negative bool) rune {
+	i := c.index(v)
+	if !negative {
+		i = len(c.chars) - i - 1
+	}
+	return c.chars[i]
+}
+
+// index returns the position in the ramp for the given brightness (light to dark, so not accounting for negative)
+func (c *Charset) index(v float64) int {
+	i := int(v / c.step)
+	if i < 0 {
+		return 0
+	}
+	if i >= len(c.chars) {
+		return len(c.chars) - 1
+	}
+	return i
+}
+
+// quantise returns the brightness the character for v represents (the middle of its range)
+func (c *Charset) quantise(v float64) float64 {
+	return (float64(c.index(v)) + 0.5) * c.step
+}
+
+// Blank returns the character used for transparent pixels (the last, and therefore sparsest character)
+func (c *Charset) Blank() rune {
+	return c.chars[len(c.chars)-1]
+}
+
+// Len returns the number of characters in the ramp
+func (c *Charset) Len() int {
+	return len(c.chars)
+}
+
+// Name returns the preset name, the file the charset was read from, or "custom"
+func (c *Charset) Name() string {
+	return c.name
+}
+
+// String returns the ramp itself
+func (c *Charset) String() string {
+	return string(c.chars)
+}
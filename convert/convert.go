@@ -2,242 +2,176 @@
 package convert
 
 import (
-	"fmt"
 	"image"
-	"strings"
-	"sync"
+	"math"
 
 	"github.com/EVODelavega/asciify/colour"
 )
 
-// CharStep
-// colours we're getting fro the image are in the 0 - 0xffff range per channel (RGB)
-// we need to figure out how to map that onto a 29 rune slice, values 0-N will be represented by
-// a single character, the next N colours move ahead in the slice and so on, so each character represents
-// this many colours: (0xFFFF * 3) / 29 or (max value per channel * number of channels) / number of chars
-// this is a float to be as precise as possible
-const CharStep = float64((65535.0 * 3.0) / 29.0)
-
-// ASCIIChars characters we'll use to build up or image
+// ASCIIChars characters we'll use to build up or image by default (the "standard" charset)
 var ASCIIChars = []rune("Ñ@#W$9876543210?!abc;:+=-,._ ")
 
-// emptyChar is used with normal scaling (accounts for height and width of characters being different)
-var emptyChar = "%s   "
-
-// emptySingleChar is used when previewing with fixed width/height. This assumes the dimensions are accounting for the
-// stretch caused by character width/height (or monospace font)
-var emptySingleChar = "%s "
-
-// PixelChar the character for a given pixel in the image
-type PixelChar struct {
-	x, y int
-	char rune
-}
-
-// ColourPixelChar is a pixel-to-char struct width added colour information
-type ColourPixelChar struct {
-	PixelChar
-	c *colour.Colour256
+// previewWidth is the number of spaces per pixel in ImgToPreview with normal scaling (accounts for height and width
+// of characters being different). When previewing with fixed width/height, a single space is used. This assumes the
+// dimensions are accounting for the stretch caused by character width/height (or monospace font)
+const previewWidth = 3
+
+// ConvertOpts are the options that can be specified when converting an image to ASCII
+type ConvertOpts struct {
+	Adjustments
+	Charset   *Charset    // the ramp to use, nil means DefaultCharset
+	Luminance Luminance   // how to determine the brightness of a pixel
+	Dither    Dither      // dithering to apply before picking characters
+	Threshold float64     // brightness (0-1) above which a braille dot is set, 0 means DefaultThreshold
+	ShapeCell image.Point // pixels per character in shape mode, zero value means DefaultShapeCell
+	// EdgeThreshold is the gradient magnitude (0-1) for a pixel to be drawn as an edge, 0 means DefaultEdgeThreshold
+	EdgeThreshold float64
+	// EdgeFill renders everything that isn't an edge using the charset, rather than leaving it blank
+	EdgeFill bool
+	// Placement determines where the colour goes in coloured output
+	Placement Placement
+	// Background is the colour partially transparent pixels are blended with, nil leaves transparent pixels
+	// transparent, so the terminal background shows through
+	Background *colour.Colour256
+	// Depth is the number of colours the terminal supports, true colour by default
+	Depth colour.Depth
+	// Quantise merges near-identical colours in coloured output, so less escape codes are needed (see EncodeOpts)
+	Quantise uint8
+	// Format is the output format, Standalone makes HTML output a complete page rather than just a <pre> block
+	// (SVG output is always a complete image). PNG, JPEG and GIF output is binary, the string returned by the ImgTo
+	// functions is the encoded image
+	Format     Format
+	Standalone bool
+	// Workers is the number of rows converted concurrently, 0 means GOMAXPROCS
+	Workers int
+	// Stats, if not nil, is set to the size of the coloured output, and how much was saved by only setting
+	// the colour when it changes
+	Stats *EncodeStats
+	// Negative swaps black and white, Invert mirrors the image (useful for webcam)
+	Negative, Invert bool
 }
 
 // ImgToPreview skips the whole "to ASCII" part of the conversion, just uses a space for pixels
 // and sets the background colour to match the image, so we can print the image in true colour
 // if true is passed for the single argument, a single space represents a pixel, otherwise we use
 // three spaces to account for character width/height being 1:3 ratio
-func ImgToPreview(img image.Image, single bool) string {
-	max := img.Bounds().Max
-	wg := sync.WaitGroup{}
-	wg.Add(max.Y)
-	done := make(chan struct{})                   // the routine that will populate the slice  will let us know when it's done with this
-	ch := make(chan ColourPixelChar, max.Y+max.X) // buffer enough for first pixels of each row + 1 column
-	matrix := make([][]string, max.Y)             // matrix[height][width]
-	format := emptyChar
-	if single {
-		format = emptySingleChar
-	}
-	// start waiting for data
-	go func() {
-		for pc := range ch {
-			// add the esc sequence and rune:
-			// matrix[pc.y][i] = pc.c.TrueEsc() + string(pc.char)
-			var cEsc string
-			if pc.c != nil {
-				cEsc = pc.c.TrueEsc()
-			}
-			matrix[pc.y][pc.x] = fmt.Sprintf(format, cEsc) // coloured space
-		}
-		close(done)
-	}()
-	for y := 0; y < max.Y; y++ {
-		matrix[y] = make([]string, max.X) // initialise each column
-		go rowColours(&wg, ch, img, y)
-	}
-	wg.Wait()
-	close(ch)
-	<-done
-	// OK, our slice is populated, convert to string:
-	chunks := make([]string, 0, len(matrix))
-	for _, r := range matrix {
-		// reset the colour after each character
-		chunks = append(chunks, strings.Join(r, colour.ResetColour))
-	}
-	delim := colour.ResetColour + "\n"
-	// return entire image as a string, end each line with colour end + new line
-	return strings.Join(chunks, delim)
+// only the adjustments, Background and colour options (depth, quantise, stats) in opts are used, there's no
+// characters to pick
+func ImgToPreview(img image.Image, single bool, opts ConvertOpts) string {
+	// The same colour is only set once, so the spaces for a pixel (and neighbouring pixels with the same colour)
+	// share the escape code
+	return opts.render(previewLayout(img, single, opts))
 }
 
 // ImgToASCIIColoured does the same as ImgToASCII, only it adds the colour escape codes to each char/pixel
-func ImgToASCIIColoured(img image.Image, negative, invert bool) string {
-	max := img.Bounds().Max
-	wg := sync.WaitGroup{}
-	wg.Add(max.Y)
-	done := make(chan struct{})                   // the routine that will populate the slice  will let us know when it's done with this
-	ch := make(chan ColourPixelChar, max.Y+max.X) // buffer enough for first pixels of each row + 1 column
-	matrix := make([][]string, max.Y)             // matrix[height][width]
-	// start waiting for data
-	go func() {
-		for pc := range ch {
-			i := pc.x
-			if invert {
-				i = len(matrix[pc.y]) - i - 1
-			}
-			// add the esc sequence and rune:
-			// matrix[pc.y][i] = pc.c.TrueEsc() + string(pc.char)
-			te := ""
-			if pc.c != nil {
-				te = pc.c.TrueEsc()
-			}
-			matrix[pc.y][i] = fmt.Sprintf("%s%c", te, pc.char)
-		}
-		close(done)
-	}()
-	for y := 0; y < max.Y; y++ {
-		matrix[y] = make([]string, max.X) // initialise each column
-		go convertRowColour(&wg, ch, img, y, negative)
-	}
-	wg.Wait()
-	close(ch)
-	<-done
-	// OK, our slice is populated, convert to string:
-	chunks := make([]string, 0, len(matrix))
-	for _, r := range matrix {
-		// reset the colour after each character
-		chunks = append(chunks, strings.Join(r, ""))
-	}
-	// return entire image as a string, end each line with colour end + new line
-	return strings.Join(chunks, "\n") + colour.ResetColour
+// where the colour goes (background, foreground or both) is determined by opts.Placement
+func ImgToASCIIColoured(img image.Image, opts ConvertOpts) string {
+	return opts.render(asciiLayout(img, opts, true))
 }
 
 // ImgToASCII converts an image to a string. By default, ligher colours will be represented by smaller characters
-// all the way down to white being shown as a space. pass in true for negative will swap this around, where spaces represent black pixels
+// all the way down to white being shown as a space. setting Negative will swap this around, where spaces represent black pixels
 // and vice-versa
-// invert will mirror the image (useful for webcam)
-func ImgToASCII(img image.Image, negative, invert bool) string {
-	max := img.Bounds().Max
-	wg := sync.WaitGroup{}
-	wg.Add(max.Y)
-	done := make(chan struct{})             // the routine that will populate the slice  will let us know when it's done with this
-	ch := make(chan PixelChar, max.Y+max.X) // buffer enough for first pixels of each row + 1 column
-	matrix := make([][]rune, max.Y)         // matrix[height][width]
-	// start waiting for data
-	go func() {
-		for pc := range ch {
-			i := pc.x
-			if invert {
-				i = len(matrix[pc.y]) - i - 1
-			}
-			matrix[pc.y][i] = pc.char
-		}
-		close(done)
-	}()
-	for y := 0; y < max.Y; y++ {
-		matrix[y] = make([]rune, max.X) // initialise each column
-		go convertRow(&wg, ch, img, y, negative)
-	}
-	wg.Wait()
-	close(ch)
-	<-done
-	// OK, our slice is populated, convert to string:
+// Invert will mirror the image (useful for webcam)
+func ImgToASCII(img image.Image, opts ConvertOpts) string {
+	// this package is not supposed to trim trailing spaces. It faithfully converts all pixels, and returns them
+	// the caller may decide to trim
+	return opts.render(asciiLayout(img, opts, false))
+}
 
-	chunks := make([]string, 0, len(matrix))
-	for _, r := range matrix {
-		// this package is not supposed to trim trailing spaces. It faithfully converts all pixels, and returns them
-		// the caller may decide to trim
-		chunks = append(chunks, string(r))
-		// chunks = append(chunks, strings.TrimRight(string(r), " "))
+func previewLayout(img image.Image, single bool, opts ConvertOpts) layout {
+	px := newPixels(img, opts.Background)
+	size := px.size
+	width := previewWidth
+	if single {
+		width = 1
+	}
+	return layout{
+		rows: size.Y,
+		cols: size.X * width,
+		fill: func(row []Cell, y int) {
+			previewRow(row, px, y, width, opts.Adjustments)
+		},
 	}
-	// return entire image as a string
-	return strings.Join(chunks, "\n")
 }
 
-func rowColours(wg *sync.WaitGroup, ch chan<- ColourPixelChar, img image.Image, y int) {
-	max := img.Bounds().Max.X
-	for x := 0; x < max; x++ {
-		// now get the escape code
-		ch <- ColourPixelChar{
-			PixelChar: PixelChar{
-				x: x,
-				y: y,
-			},
-			c: colour.FromColor(img.At(x, y)),
+// previewRow populates a row of coloured spaces, width spaces per pixel
+func previewRow(row []Cell, px pixels, y, width int, adj Adjustments) {
+	rc := newRowColours(len(row) / width)
+	for x := 0; x < len(row)/width; x++ {
+		c := rc.pixel(px, adj, x, y)
+		for i := 0; i < width; i++ {
+			row[x*width+i] = Cell{Char: ' ', BG: c}
 		}
 	}
-	wg.Done()
 }
 
-func convertRowColour(wg *sync.WaitGroup, ch chan<- ColourPixelChar, img image.Image, y int, reverse bool) {
-	max := img.Bounds().Max.X
-	cLen := len(ASCIIChars)
-	for x := 0; x < max; x++ {
-		// alpha is already applied, so we can just ignore it
-		i := 0
-		c := img.At(x, y)
-		r, g, b, a := c.RGBA()
-		if a == 0 {
-			// alpha on max, space character
-			i = cLen - 1
-		} else {
-			i = int(float64(r+g+b) / CharStep)
-			if !reverse {
-				i = cLen - i
-			}
-		}
-		pc := PixelChar{
-			char: ASCIIChars[i%cLen],
-			x:    x,
-			y:    y,
+// asciiLayout maps each pixel onto a character, the colour is only set if coloured is true
+func asciiLayout(img image.Image, opts ConvertOpts, coloured bool) layout {
+	cs := opts.charset()
+	px := newPixels(img, opts.Background)
+	levels := opts.levels(px, cs)
+	return layout{
+		rows: px.size.Y,
+		cols: px.size.X,
+		fill: func(row []Cell, y int) {
+			convertRow(row, px, y, cs, opts, levels[y], coloured)
+		},
+	}
+}
+
+// convertRow levels is the dithered brightness of the row, nil if we're not dithering
+func convertRow(row []Cell, px pixels, y int, cs *Charset, opts ConvertOpts, levels []float64, coloured bool) {
+	var rc *rowColours
+	if coloured {
+		// the contrasting background needs a colour as well
+		rc = newRowColours(2 * len(row))
+	}
+	for x := range row {
+		i := x
+		if opts.Invert {
+			i = len(row) - x - 1
 		}
-		// now get the escape code
-		ch <- ColourPixelChar{
-			PixelChar: pc,
-			c:         colour.FromColor(c),
+		r, g, b, a := px.RGBA(x, y)
+		char := opts.char(cs, r, g, b, a, levels, x)
+		if !coloured {
+			row[i] = Cell{Char: char}
+			continue
 		}
+		row[i] = opts.Placement.cell(char, rc.rgba(opts.Adjustments, r, g, b, a), rc)
+	}
+}
 
+// charset returns the charset to use, falls back to the default
+func (o ConvertOpts) charset() *Charset {
+	if o.Charset == nil {
+		return DefaultCharset
 	}
-	wg.Done()
+	return o.Charset
 }
 
-func convertRow(wg *sync.WaitGroup, ch chan<- PixelChar, img image.Image, y int, reverse bool) {
-	max := img.Bounds().Max.X
-	cLen := len(ASCIIChars)
-	for x := 0; x < max; x++ {
-		// alpha is already applied, so we can just ignore it
-		i := 0
-		r, g, b, a := img.At(x, y).RGBA()
-		if a == 0 {
-			// alpha on max, space character
-			i = cLen - 1
-		} else {
-			i = int(float64(r+g+b) / CharStep)
-			if !reverse {
-				i = cLen - i
-			}
-		}
-		ch <- PixelChar{
-			char: ASCIIChars[i%cLen],
-			x:    x,
-			y:    y,
+// levels returns the dithered brightness for each pixel. If we're not dithering, this returns a slice of nil rows
+// and the brightness is computed per pixel by char
+func (o ConvertOpts) levels(px pixels, cs *Charset) [][]float64 {
+	if o.Dither == NoDither {
+		return make([][]float64, px.size.Y)
+	}
+	return ditherGrid(px, charsetQuantiser(cs), o)
+}
+
+// char returns the character for the given pixel (as returned by color.Color.RGBA): the luminance, adjusted and mapped onto the charset
+// if levels is not nil, the (dithered) brightness at position x is used instead
+func (o ConvertOpts) char(cs *Charset, r, g, b, a uint32, levels []float64, x int) rune {
+	if levels != nil {
+		if math.IsNaN(levels[x]) {
+			return cs.Blank()
 		}
+		return cs.Rune(levels[x], o.Negative)
+	}
+	// alpha is already applied, so we can just ignore it
+	if a == 0 {
+		// alpha on max, space character
+		return cs.Blank()
 	}
-	wg.Done()
+	return cs.Rune(o.Apply(o.Luminance.Of(r, g, b)), o.Negative)
 }
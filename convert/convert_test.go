@@ -0,0 +1,118 @@
+package convert
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/EVODelavega/asciify/colour"
+	"github.com/EVODelavega/asciify/scale"
+)
+
+var renderers = map[string]func(image.Image, ConvertOpts) string{
+	"ascii":          ImgToASCII,
+	"ascii coloured": ImgToASCIIColoured,
+	"preview": func(img image.Image, opts ConvertOpts) string {
+		return ImgToPreview(img, false, opts)
+	},
+	"preview single": func(img image.Image, opts ConvertOpts) string {
+		return ImgToPreview(img, true, opts)
+	},
+	"half":             ImgToHalfBlock,
+	"quadrant":         ImgToQuadrant,
+	"sextant":          ImgToSextant,
+	"braille":          ImgToBraille,
+	"braille coloured": ImgToBrailleColoured,
+	"shape":            ImgToShape,
+	"edge":             ImgToEdges,
+}
+
+// testPattern draws a pattern with plenty of edges, and some partially transparent pixels, into img
+func testPattern(img *image.RGBA) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.RGBA{
+				R: uint8(x * 37),
+				G: uint8(y * 23),
+				B: uint8((x ^ y) * 11),
+				A: 0xff,
+			}
+			if (x/4+y/4)%2 == 0 {
+				c = color.RGBA{R: c.R, A: 0xff}
+			}
+			if x%7 == 0 {
+				// premultiplied, so the colour can't be brighter than the alpha
+				c = color.RGBA{R: c.R / 2, G: c.G / 2, B: c.B / 2, A: 0x80}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// copyAt copies the pixels of img into a new image starting at 0, 0
+func copyAt(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(x-b.Min.X, y-b.Min.Y, img.At(x, y))
+		}
+	}
+	return dst
+}
+
+// TestSubImage checks all renderers produce the same output for a sub-image (bounds not starting at 0, 0) as they
+// do for a copy of the same pixels starting at 0, 0
+func TestSubImage(t *testing.T) {
+	full := image.NewRGBA(image.Rect(0, 0, 48, 40))
+	testPattern(full)
+	// an image that doesn't start at 0, 0 to begin with, cropped relative to its top left corner
+	offset := image.NewRGBA(image.Rect(-13, 9, 35, 49))
+	testPattern(offset)
+	area := image.Rect(5, 3, 37, 31)
+	sub := full.SubImage(area)
+	cropped, err := scale.Crop(full, area)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsetCropped, err := scale.Crop(offset, area)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := offsetCropped.Bounds(); got != area.Add(offset.Rect.Min) {
+		t.Fatalf("expected crop of the offset image to have bounds %v, got %v", area.Add(offset.Rect.Min), got)
+	}
+	images := map[string]image.Image{
+		"SubImage":           sub,
+		"Crop":               cropped,
+		"Crop offset image":  offsetCropped,
+		"copy of the pixels": copyAt(sub),
+	}
+	bg := colour.Colour256{R: 0x20, G: 0x40, B: 0x60}
+	options := map[string]ConvertOpts{
+		"default": {},
+		"options": {
+			Dither:     BayerDither,
+			Invert:     true,
+			Background: &bg,
+			Depth:      colour.Colours256,
+		},
+	}
+	for oname, opts := range options {
+		for name, render := range renderers {
+			// the copy of the offset image is different, so compare against the copy of its own pixels
+			want := render(copyAt(sub), opts)
+			wantOffset := render(copyAt(offsetCropped), opts)
+			for iname, img := range images {
+				expect := want
+				if img == offsetCropped {
+					expect = wantOffset
+				}
+				if got := render(img, opts); got != expect {
+					t.Errorf("%s (%s): output for %s differs from the output for a copy at 0, 0", name, oname, iname)
+				}
+			}
+		}
+	}
+}
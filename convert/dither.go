@@ -0,0 +1,173 @@
+package convert
+
+import (
+	"errors"
+	"math"
+)
+
+// Dither the dithering algorithm to use when mapping brightness onto the charset
+type Dither uint32
+
+const (
+	// NoDither just maps each pixel onto the closest character
+	NoDither Dither = iota
+	// FloydSteinbergDither diffuses the full quantisation error over the 4 neighbouring pixels
+	FloydSteinbergDither
+	// AtkinsonDither diffuses 3/4 of the error over 6 pixels, which keeps more contrast
+	AtkinsonDither
+	// BayerDither is an ordered dither (4x4 Bayer matrix), doesn't diffuse anything, so it's fast and stable
+	// which is what you want for video (asciicam)
+	BayerDither
+)
+
+// diffusion is how much of the error is pushed to the pixel at the given offset
+type diffusion struct {
+	dx, dy int
+	w      float64
+}
+
+var (
+	ErrInvalidDither = errors.New("specified dither mode not supported")
+
+	ditherStr = map[Dither]string{
+		NoDither:             "none",
+		FloydSteinbergDither: "floyd",
+		AtkinsonDither:       "atkinson",
+		BayerDither:          "bayer",
+	}
+
+	// DitherModes all supported dithering algorithms, in the order we list them
+	DitherModes = []Dither{
+		NoDither,
+		FloydSteinbergDither,
+		AtkinsonDither,
+		BayerDither,
+	}
+
+	kernels = map[Dither][]diffusion{
+		FloydSteinbergDither: {
+			{dx: 1, dy: 0, w: 7.0 / 16.0},
+			{dx: -1, dy: 1, w: 3.0 / 16.0},
+			{dx: 0, dy: 1, w: 5.0 / 16.0},
+			{dx: 1, dy: 1, w: 1.0 / 16.0},
+		},
+		AtkinsonDither: {
+			{dx: 1, dy: 0, w: 1.0 / 8.0},
+			{dx: 2, dy: 0, w: 1.0 / 8.0},
+			{dx: -1, dy: 1, w: 1.0 / 8.0},
+			{dx: 0, dy: 1, w: 1.0 / 8.0},
+			{dx: 1, dy: 1, w: 1.0 / 8.0},
+			{dx: 0, dy: 2, w: 1.0 / 8.0},
+		},
+	}
+
+	bayer4 = [4][4]float64{
+		{0, 8, 2, 10},
+		{12, 4, 14, 6},
+		{3, 11, 1, 9},
+		{15, 7, 13, 5},
+	}
+)
+
+// ParseDither returns the dither mode for a given name (as returned by String)
+func ParseDither(name string) (Dither, error) {
+	for d, s := range ditherStr {
+		if s == name {
+			return d, nil
+		}
+	}
+	return NoDither, ErrInvalidDither
+}
+
+// String returns the dither mode name
+func (d Dither) String() string {
+	s, ok := ditherStr[d]
+	if !ok {
+		return ""
+	}
+	return s
+}
+
+// quantiser maps a brightness value onto the nearest value we can actually display, step is the range
+// of values each output level covers (used to scale the ordered dither offsets)
+type quantiser struct {
+	quantise func(v float64) float64
+	step     float64
+}
+
+// charsetQuantiser for when we're mapping pixels onto a ramp
+func charsetQuantiser(cs *Charset) quantiser {
+	return quantiser{
+		quantise: cs.quantise,
+		step:     cs.step,
+	}
+}
+
+// thresholdQuantiser for when pixels can only be on or off (braille)
+func thresholdQuantiser(t float64) quantiser {
+	return quantiser{
+		quantise: func(v float64) float64 {
+			if v >= t {
+				return 1
+			}
+			return 0
+		},
+		step: 1,
+	}
+}
+
+// ditherGrid returns the brightness of each pixel after dithering, transparent pixels are set to NaN.
+// The brightness values are computed concurrently, like the rest of the conversion, but error
+// diffusion has to go through the rows in order, so that bit is done once all rows are in
+func ditherGrid(px pixels, q quantiser, opts ConvertOpts) [][]float64 {
+	grid := make([][]float64, px.size.Y)
+	buf := make([]float64, px.size.X*px.size.Y)
+	for y := range grid {
+		grid[y] = buf[y*px.size.X : (y+1)*px.size.X]
+	}
+	forEachRow(px.size.Y, opts.Workers, func(y int) {
+		levelRow(grid[y], px, y, q, opts)
+	})
+	if kernel, ok := kernels[opts.Dither]; ok {
+		diffuse(grid, q, kernel)
+	}
+	return grid
+}
+
+// levelRow populates the row with the adjusted brightness values, ordered dithering is applied here, too
+func levelRow(row []float64, px pixels, y int, q quantiser, opts ConvertOpts) {
+	for x := range row {
+		r, g, b, a := px.RGBA(x, y)
+		if a == 0 {
+			row[x] = math.NaN()
+			continue
+		}
+		v := opts.Apply(opts.Luminance.Of(r, g, b))
+		if opts.Dither == BayerDither {
+			// shift the value by up to half a step either way
+			v += ((bayer4[y%4][x%4]+0.5)/16 - 0.5) * q.step
+		}
+		row[x] = v
+	}
+}
+
+// diffuse quantises every value in the grid, pushing the error onto the neighbouring pixels as per the kernel
+func diffuse(grid [][]float64, q quantiser, kernel []diffusion) {
+	for y, row := range grid {
+		for x, v := range row {
+			if math.IsNaN(v) {
+				continue
+			}
+			qv := q.quantise(v)
+			row[x] = qv
+			e := v - qv
+			for _, d := range kernel {
+				ny, nx := y+d.dy, x+d.dx
+				if ny >= len(grid) || nx < 0 || nx >= len(row) || math.IsNaN(grid[ny][nx]) {
+					continue
+				}
+				grid[ny][nx] += e * d.w
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package convert
+
+import (
+	"image"
+	"math"
+)
+
+// DefaultEdgeThreshold is the gradient magnitude (0-1) from which a pixel is considered to be part of an edge
+const DefaultEdgeThreshold = 0.2
+
+// ImgToEdges renders the outlines of the image: a Sobel filter is applied to the brightness of the image,
+// and pixels on a strong edge are shown using a character matching the direction of the edge (-, |, / or \).
+// Edges are thinned (non-maximum suppression), so lines are a single character wide. Everything else is
+// a space, or the normal ASCII character if EdgeFill is set
+func ImgToEdges(img image.Image, opts ConvertOpts) string {
+	return opts.render(edgeLayout(img, opts))
+}
+
+func edgeLayout(img image.Image, opts ConvertOpts) layout {
+	cs := opts.charset()
+	px := newPixels(img, opts.Background)
+	size := px.size
+	// the edges are detected on the brightness without dithering
+	plain := opts
+	plain.Dither = NoDither
+	luma := ditherGrid(px, charsetQuantiser(cs), plain)
+	levels := opts.levels(px, cs)
+	return layout{
+		rows: size.Y,
+		cols: size.X,
+		fill: func(row []Cell, y int) {
+			edgeRow(row, px, luma, levels[y], y, cs, opts)
+		},
+	}
+}
+
+func edgeRow(row []Cell, px pixels, luma [][]float64, levels []float64, y int, cs *Charset, opts ConvertOpts) {
+	t := opts.edgeThreshold()
+	for x := range row {
+		i := x
+		if opts.Invert {
+			i = len(row) - x - 1
+		}
+		gx, gy := sobel(luma, x, y)
+		mag := math.Hypot(gx, gy)
+		if mag >= t && isPeak(luma, x, y, gx, gy, mag) {
+			row[i] = Cell{Char: edgeRune(gx, gy, opts.Invert)}
+			continue
+		}
+		if opts.EdgeFill {
+			r, g, b, a := px.RGBA(x, y)
+			row[i] = Cell{Char: opts.char(cs, r, g, b, a, levels, x)}
+		} else {
+			row[i] = Cell{Char: ' '}
+		}
+	}
+}
+
+// sobel returns the gradient at x, y, normalised so the magnitude is in the 0-1 range for
+// a step from black to white. Pixels outside of the image use the value of the closest edge pixel
+func sobel(luma [][]float64, x, y int) (float64, float64) {
+	p := func(dx, dy int) float64 {
+		py, px := clampInt(y+dy, len(luma)), clampInt(x+dx, len(luma[0]))
+		v := luma[py][px]
+		if math.IsNaN(v) {
+			// transparent, so no ink
+			return 0
+		}
+		return v
+	}
+	gx := (p(1, -1) + 2*p(1, 0) + p(1, 1)) - (p(-1, -1) + 2*p(-1, 0) + p(-1, 1))
+	gy := (p(-1, 1) + 2*p(0, 1) + p(1, 1)) - (p(-1, -1) + 2*p(0, -1) + p(1, -1))
+	return gx / 4, gy / 4
+}
+
+// isPeak is the non-maximum suppression: the pixel is only kept if the gradient magnitude is stronger than that of
+// the next pixel in the direction of the gradient, and at least as strong as the previous one. A step edge gives
+// both pixels on either side of the step the same magnitude, only one of them should be kept
+func isPeak(luma [][]float64, x, y int, gx, gy, mag float64) bool {
+	dx, dy := int(math.Round(gx/mag)), int(math.Round(gy/mag))
+	neighbours := []image.Point{{X: dx, Y: dy}}
+	if dx != 0 && dy != 0 {
+		// the diagonal neighbours are further apart, a diagonal edge runs through 2 pixels on each row, so those
+		// are compared as well
+		neighbours = append(neighbours, image.Point{X: dx})
+	}
+	for _, d := range neighbours {
+		for _, s := range []int{1, -1} {
+			n, ok := magnitude(luma, x+s*d.X, y+s*d.Y)
+			if ok && (n > mag || (s == 1 && n == mag)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// magnitude returns the gradient magnitude at x, y, false if the pixel is outside of the image
+func magnitude(luma [][]float64, x, y int) (float64, bool) {
+	if y < 0 || y >= len(luma) || x < 0 || x >= len(luma[0]) {
+		return 0, false
+	}
+	return math.Hypot(sobel(luma, x, y)), true
+}
+
+// edgeRune returns the character for an edge, which runs perpendicular to the gradient. The y axis points down
+// so a gradient pointing down and to the right means a / shaped edge. Mirroring the image swaps / and \
+func edgeRune(gx, gy float64, mirror bool) rune {
+	a := math.Atan2(gy, gx) * 180 / math.Pi
+	if a < 0 {
+		a += 180
+	}
+	var r rune
+	switch {
+	case a < 22.5 || a >= 157.5:
+		return '|'
+	case a < 67.5:
+		r = '/'
+	case a < 112.5:
+		return '-'
+	default:
+		r = '\\'
+	}
+	if mirror {
+		if r == '/' {
+			return '\\'
+		}
+		return '/'
+	}
+	return r
+}
+
+func clampInt(v, n int) int {
+	if v < 0 {
+		return 0
+	}
+	if v >= n {
+		return n - 1
+	}
+	return v
+}
+
+// edgeThreshold returns the threshold to use, falls back to the default
+func (o ConvertOpts) edgeThreshold() float64 {
+	if o.EdgeThreshold <= 0 {
+		return DefaultEdgeThreshold
+	}
+	return o.EdgeThreshold
+}
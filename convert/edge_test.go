@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// stepImage returns a black image, with the pixels for which white returns true set to white
+func stepImage(w, h int, white func(x, y int) bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{A: 0xff}
+			if white(x, y) {
+				c = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// edgeCells returns the positions of all characters that aren't a space
+func edgeCells(out string) []image.Point {
+	var cells []image.Point
+	for y, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		for x, c := range []rune(line) {
+			if c != ' ' {
+				cells = append(cells, image.Pt(x, y))
+			}
+		}
+	}
+	return cells
+}
+
+func TestEdgesSingleWidth(t *testing.T) {
+	tests := []struct {
+		name  string
+		white func(x, y int) bool
+		char  rune
+	}{
+		{
+			name:  "vertical step",
+			white: func(x, y int) bool { return x >= 10 },
+			char:  '|',
+		},
+		{
+			name:  "vertical step, white to black",
+			white: func(x, y int) bool { return x < 10 },
+			char:  '|',
+		},
+		{
+			name:  "horizontal step",
+			white: func(x, y int) bool { return y >= 5 },
+			char:  '-',
+		},
+	}
+	for _, tt := range tests {
+		out := ImgToEdges(stepImage(20, 12, tt.white), ConvertOpts{})
+		lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+		cells := edgeCells(out)
+		// one character per row for a vertical edge, one per column for a horizontal one
+		want := len(lines)
+		if tt.char == '-' {
+			want = len([]rune(lines[0]))
+		}
+		if len(cells) != want {
+			t.Errorf("%s: expected a line of %d characters, got %d:\n%s", tt.name, want, len(cells), out)
+			continue
+		}
+		for _, p := range cells {
+			if c := []rune(lines[p.Y])[p.X]; c != tt.char {
+				t.Errorf("%s: expected %q at %v, got %q", tt.name, tt.char, p, c)
+			}
+			if tt.char == '|' && p.X != cells[0].X || tt.char == '-' && p.Y != cells[0].Y {
+				t.Errorf("%s: edge isn't straight:\n%s", tt.name, out)
+				break
+			}
+		}
+	}
+}
+
+func TestEdgesDiagonal(t *testing.T) {
+	// the image is wider than the diagonal, pixels outside of the image are clamped, which makes the corners messy
+	out := ImgToEdges(stepImage(24, 16, func(x, y int) bool { return x > y+2 }), ConvertOpts{})
+	rows := map[int]int{}
+	for _, p := range edgeCells(out) {
+		rows[p.Y]++
+	}
+	for y, n := range rows {
+		if n > 1 {
+			t.Errorf("row %d has %d edge characters, expected 1:\n%s", y, n, out)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+// EncodeOpts are the options used to turn a Grid into a string
+type EncodeOpts struct {
+	// Depth is the number of colours the terminal supports, true colour by default
+	Depth colour.Depth
+	// Quantise rounds each colour channel to a multiple of this value, so near-identical colours end up using the
+	// same escape code and can share it. 0 or 1 leaves the colours as they are. This is mostly useful with true
+	// colour: with 256 or 16 colours, similar colours already map onto the same palette entry
+	Quantise uint8
+}
+
+// EncodeStats shows how much the output was reduced by only emitting escape codes when the colour changes
+type EncodeStats struct {
+	// Bytes is the size of the encoded output
+	Bytes int
+	// Naive is the size the output would've been setting the colour for, and resetting it after, every cell
+	Naive int
+}
+
+// Saved returns the number of bytes saved
+func (s EncodeStats) Saved() int {
+	return s.Naive - s.Bytes
+}
+
+// Ratio returns how many times smaller the output is (naive size / actual size)
+func (s EncodeStats) Ratio() float64 {
+	if s.Bytes == 0 {
+		return 1
+	}
+	return float64(s.Naive) / float64(s.Bytes)
+}
+
+// String returns a human readable summary of the stats
+func (s EncodeStats) String() string {
+	return fmt.Sprintf("%d bytes, saved %d bytes (%.1fx smaller)", s.Bytes, s.Saved(), s.Ratio())
+}
+
+// EncodeWith returns the grid as a string. Escape codes are only written when the colour changes, and the colour
+// is only reset when a cell has less colours than the one before it, and at the end of each line (so the newline
+// doesn't get a background colour). The foreground colour of a space isn't visible, so it's left alone
+func (g Grid) EncodeWith(opts EncodeOpts) (string, EncodeStats) {
+	sb := strings.Builder{}
+	enc := encoder{
+		w:    &sb,
+		opts: opts,
+	}
+	for y, row := range g {
+		if y > 0 {
+			enc.newline()
+		}
+		enc.row(row)
+	}
+	return sb.String(), enc.stats
+}
+
+// textWriter is what we need to write the output, implemented by both strings.Builder and bufio.Writer. Write errors
+// are ignored by the encoder: strings.Builder doesn't return any, and bufio.Writer returns the error when flushing.
+// Write is used by the image formats, those are encoded using the image encoders
+type textWriter interface {
+	io.Writer
+	WriteString(s string) (int, error)
+	WriteRune(r rune) (int, error)
+}
+
+// encoder writes rows of cells as ANSI text, keeping track of the stats
+type encoder struct {
+	w     textWriter
+	opts  EncodeOpts
+	stats EncodeStats
+	// the escape codes are written into these buffers, they're reused so escape codes don't need a string each
+	fg, bg           []byte
+	fgCache, bgCache escCache
+}
+
+// row writes a single row, see EncodeWith
+func (e *encoder) row(row []Cell) {
+	// the escape codes that are currently active, empty if there's no colour
+	fg, bg := e.fg[:0], e.bg[:0]
+	for _, c := range row {
+		e.stats.Naive += utf8.RuneLen(c.Char)
+		if e.opts.Depth == colour.NoColour || (c.FG == nil && c.BG == nil) {
+			if len(bg) > 0 || (len(fg) > 0 && c.Char != ' ') {
+				e.str(colour.ResetColour)
+				fg, bg = fg[:0], bg[:0]
+			}
+			e.char(c.Char)
+			continue
+		}
+		wantFG, naiveFG := e.esc(&e.fgCache, c.FG, true)
+		wantBG, naiveBG := e.esc(&e.bgCache, c.BG, false)
+		e.stats.Naive += len(colour.ResetColour) + naiveFG + naiveBG
+		if (len(wantFG) == 0 && len(fg) > 0 && c.Char != ' ') || (len(wantBG) == 0 && len(bg) > 0) {
+			// there's no escape code to turn off just one of the colours that works everywhere, so reset both
+			e.str(colour.ResetColour)
+			fg, bg = fg[:0], bg[:0]
+		}
+		// can't see the foreground colour of a space, just keep whatever is there
+		if c.Char != ' ' && !bytes.Equal(wantFG, fg) {
+			fg = append(fg[:0], wantFG...)
+			e.write(fg)
+		}
+		if !bytes.Equal(wantBG, bg) {
+			bg = append(bg[:0], wantBG...)
+			e.write(bg)
+		}
+		e.char(c.Char)
+	}
+	if len(fg) > 0 || len(bg) > 0 {
+		e.str(colour.ResetColour)
+	}
+	e.fg, e.bg = fg, bg
+}
+
+func (e *encoder) begin(rows, cols int) {}
+
+func (e *encoder) end() {}
+
+func (e *encoder) newline() {
+	e.stats.Naive++
+	e.str("\n")
+}
+
+func (e *encoder) str(s string) {
+	n, _ := e.w.WriteString(s)
+	e.stats.Bytes += n
+}
+
+func (e *encoder) char(r rune) {
+	n, _ := e.w.WriteRune(r)
+	e.stats.Bytes += n
+}
+
+func (e *encoder) write(b []byte) {
+	n, _ := e.w.Write(b)
+	e.stats.Bytes += n
+}
+
+// escCache holds the last escape code, neighbouring cells often have the same colour
+type escCache struct {
+	c     colour.Colour256
+	set   bool
+	esc   []byte
+	naive int
+}
+
+// esc returns the escape code for the colour, and the length of the escape code the per-cell encoding would've
+// used (for the stats). The last escape code is cached, creating them is the most expensive part of encoding. The
+// escape code is only valid until the next call
+func (e *encoder) esc(cache *escCache, c *colour.Colour256, fg bool) ([]byte, int) {
+	if c == nil {
+		return nil, 0
+	}
+	if cache.set && cache.c == *c {
+		return cache.esc, cache.naive
+	}
+	cache.c, cache.set = *c, true
+	if e.opts.Quantise > 1 {
+		// the naive encoding doesn't quantise
+		cache.esc = c.AppendEsc(cache.esc[:0], e.opts.Depth, fg)
+		cache.naive = len(cache.esc)
+	}
+	cache.esc = e.opts.quantise(*c).AppendEsc(cache.esc[:0], e.opts.Depth, fg)
+	if e.opts.Quantise <= 1 {
+		cache.naive = len(cache.esc)
+	}
+	return cache.esc, cache.naive
+}
+
+// quantise returns the quantised colour (if Quantise is set)
+func (o EncodeOpts) quantise(c colour.Colour256) colour.Colour256 {
+	if o.Quantise <= 1 {
+		return c
+	}
+	return colour.Colour256{
+		R: quantiseChannel(c.R, o.Quantise),
+		G: quantiseChannel(c.G, o.Quantise),
+		B: quantiseChannel(c.B, o.Quantise),
+	}
+}
+
+// quantiseChannel rounds v to the nearest multiple of q, without going over 255
+func quantiseChannel(v, q uint8) uint8 {
+	r := (uint(v) + uint(q)/2) / uint(q) * uint(q)
+	if r > 255 {
+		r -= uint(q)
+	}
+	return uint8(r)
+}
+
+// encodeOpts returns the options used to encode the grid
+func (o ConvertOpts) encodeOpts() EncodeOpts {
+	return EncodeOpts{
+		Depth:    o.Depth,
+		Quantise: o.Quantise,
+	}
+}
+
+// encode turns the grid into a string in the output format, and records the stats if requested
+func (o ConvertOpts) encode(g Grid) string {
+	sb := strings.Builder{}
+	rw := o.rowWriter(&sb)
+	cols := 0
+	if len(g) > 0 {
+		cols = len(g[0])
+	}
+	rw.begin(len(g), cols)
+	for y, row := range g {
+		if y > 0 {
+			rw.newline()
+		}
+		rw.row(row)
+	}
+	rw.end()
+	o.recordStats(rw)
+	return sb.String()
+}
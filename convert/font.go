@@ -0,0 +1,35 @@
+package convert
+
+import (
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+)
+
+var (
+	monoOnce sync.Once
+	monoFont *opentype.Font
+)
+
+// monoFace returns a face for the bundled monospace font (Go Mono) at the given size in pixels
+// the font is compiled in, so failing to parse it is a bug, hence the panic
+func monoFace(size float64) font.Face {
+	monoOnce.Do(func() {
+		f, err := opentype.Parse(gomono.TTF)
+		if err != nil {
+			panic(err)
+		}
+		monoFont = f
+	})
+	face, err := opentype.NewFace(monoFont, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return face
+}
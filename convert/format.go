@@ -0,0 +1,128 @@
+package convert
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the output format
+type Format uint32
+
+const (
+	// ANSIFormat is text with terminal escape codes for the colours, the default
+	ANSIFormat Format = iota
+	// HTMLFormat is a <pre> block, with a span for every run of characters in the same colour
+	HTMLFormat
+	// SVGFormat is an SVG image, with the characters laid out on a grid
+	SVGFormat
+	// PNGFormat is a PNG image of the output, drawn using the bundled monospace font
+	PNGFormat
+	// JPEGFormat is the same as PNGFormat, only JPEG encoded
+	JPEGFormat
+	// GIFFormat is the same as PNGFormat, only GIF encoded. Renderer.GIF renders animations in this format
+	GIFFormat
+)
+
+var (
+	ErrInvalidFormat = errors.New("specified output format not supported")
+
+	formatStr = map[Format]string{
+		ANSIFormat: "ansi",
+		HTMLFormat: "html",
+		SVGFormat:  "svg",
+		PNGFormat:  "png",
+		JPEGFormat: "jpeg",
+		GIFFormat:  "gif",
+	}
+
+	// formatExt maps file extensions onto the format to use, anything else is ANSIFormat
+	formatExt = map[string]Format{
+		"html": HTMLFormat,
+		"htm":  HTMLFormat,
+		"svg":  SVGFormat,
+		"png":  PNGFormat,
+		"jpg":  JPEGFormat,
+		"jpeg": JPEGFormat,
+		"gif":  GIFFormat,
+	}
+
+	// Formats all supported output formats
+	Formats = []Format{
+		ANSIFormat,
+		HTMLFormat,
+		SVGFormat,
+		PNGFormat,
+		JPEGFormat,
+		GIFFormat,
+	}
+)
+
+// rowWriter writes the output in a given format, one row at a time
+type rowWriter interface {
+	// begin is called before the first row with the size of the grid, end after the last row
+	begin(rows, cols int)
+	row(row []Cell)
+	newline()
+	end()
+}
+
+// ParseFormat returns the format for a given name (as returned by String)
+func ParseFormat(name string) (Format, error) {
+	for f, s := range formatStr {
+		if s == name {
+			return f, nil
+		}
+	}
+	return ANSIFormat, ErrInvalidFormat
+}
+
+// FormatFromFile returns the format to use for a file based on its extension. If the extension doesn't have
+// a specific format, ANSIFormat and false are returned
+func FormatFromFile(path string) (Format, bool) {
+	f, ok := formatExt[strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))]
+	return f, ok
+}
+
+// String returns the format name
+func (f Format) String() string {
+	s, ok := formatStr[f]
+	if !ok {
+		return ""
+	}
+	return s
+}
+
+// rowWriter returns the writer for the output format
+func (o ConvertOpts) rowWriter(w textWriter) rowWriter {
+	switch o.Format {
+	case PNGFormat, JPEGFormat, GIFFormat:
+		return &rasterWriter{
+			w:      w,
+			opts:   o.encodeOpts(),
+			format: o.Format,
+		}
+	case SVGFormat:
+		return &svgWriter{
+			w:    w,
+			opts: o.encodeOpts(),
+		}
+	case HTMLFormat:
+		return &htmlWriter{
+			w:          w,
+			opts:       o.encodeOpts(),
+			standalone: o.Standalone,
+		}
+	}
+	return &encoder{
+		w:    w,
+		opts: o.encodeOpts(),
+	}
+}
+
+// recordStats sets the stats if requested, only ANSI output keeps track of those
+func (o ConvertOpts) recordStats(rw rowWriter) {
+	if e, ok := rw.(*encoder); ok && o.Stats != nil {
+		*o.Stats = e.stats
+	}
+}
@@ -0,0 +1,66 @@
+package convert
+
+import (
+	"image"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+const (
+	upperHalf = '▀'
+	lowerHalf = '▄'
+)
+
+// ImgToHalfBlock renders 2 pixels per character: the upper half block is coloured using the foreground
+// colour (top pixel), and the background colour shows the bottom pixel. This doubles the vertical resolution
+// compared to ImgToPreview, and because characters are about twice as high as they are wide, the pixels end up
+// being square. Only the adjustments, Invert, Background and the colour options (depth, quantise, stats) are
+// used from opts
+func ImgToHalfBlock(img image.Image, opts ConvertOpts) string {
+	return opts.render(halfBlockLayout(img, opts))
+}
+
+func halfBlockLayout(img image.Image, opts ConvertOpts) layout {
+	px := newPixels(img, opts.Background)
+	size := px.size
+	return layout{
+		rows: (size.Y + 1) / 2, // odd height: the last line only has a top half
+		cols: size.X,
+		fill: func(row []Cell, y int) {
+			halfBlockRow(row, px, y*2, opts)
+		},
+	}
+}
+
+// halfBlockRow populates a row of cells from image line y (top) and y+1 (bottom)
+func halfBlockRow(row []Cell, px pixels, y int, opts ConvertOpts) {
+	rc := newRowColours(2 * len(row))
+	for x := range row {
+		top := rc.pixel(px, opts.Adjustments, x, y)
+		var bottom *colour.Colour256
+		if y+1 < px.size.Y {
+			bottom = rc.pixel(px, opts.Adjustments, x, y+1)
+		}
+		i := x
+		if opts.Invert {
+			i = len(row) - x - 1
+		}
+		row[i] = halfBlockCell(top, bottom)
+	}
+}
+
+// halfBlockCell picks the character and colours. Transparent pixels (nil) mean we can't use the background
+// colour for that half, so we use the lower half block if only the top is transparent, or just a space if both are
+func halfBlockCell(top, bottom *colour.Colour256) Cell {
+	switch {
+	case top == nil && bottom == nil:
+		return Cell{Char: ' '}
+	case top == nil:
+		return Cell{Char: lowerHalf, FG: bottom}
+	}
+	return Cell{
+		Char: upperHalf,
+		FG:   top,
+		BG:   bottom,
+	}
+}
@@ -0,0 +1,129 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+const (
+	// preStyle makes the lines about twice as high as a character is wide (monospace fonts are ~0.6em wide), like
+	// they are in a terminal, so the image has the right aspect ratio
+	preStyle = "font-family: monospace; line-height: 1.2; letter-spacing: 0;"
+
+	htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>asciify</title>
+<style>
+body { background: #000; color: #fff; }
+</style>
+</head>
+<body>
+`
+	htmlFooter = `</body>
+</html>
+`
+)
+
+// htmlWriter writes the rows as a <pre> block. Runs of characters with the same colours share a span
+type htmlWriter struct {
+	w          textWriter
+	opts       EncodeOpts
+	standalone bool
+}
+
+func (h *htmlWriter) begin(rows, cols int) {
+	if h.standalone {
+		h.w.WriteString(htmlHeader)
+	}
+	h.w.WriteString(`<pre style="` + preStyle + `">`)
+}
+
+func (h *htmlWriter) row(row []Cell) {
+	// the style of the span we're in, empty if there's no span
+	var style, fg string
+	for _, c := range row {
+		cfg, bg := h.opts.css(c.FG), h.opts.css(c.BG)
+		if c.Char == ' ' {
+			// the colour of a space isn't visible, so it can go in the current span
+			cfg = fg
+		}
+		s := ""
+		if cfg != "" {
+			s = "color: " + cfg + ";"
+		}
+		if bg != "" {
+			s += "background-color: " + bg + ";"
+		}
+		if s != style {
+			if style != "" {
+				h.w.WriteString("</span>")
+			}
+			if s != "" {
+				h.w.WriteString(`<span style="` + s + `">`)
+			}
+			style, fg = s, cfg
+		}
+		h.char(c.Char)
+	}
+	if style != "" {
+		h.w.WriteString("</span>")
+	}
+}
+
+func (h *htmlWriter) newline() {
+	h.w.WriteString("\n")
+}
+
+func (h *htmlWriter) end() {
+	h.w.WriteString("</pre>\n")
+	if h.standalone {
+		h.w.WriteString(htmlFooter)
+	}
+}
+
+// char writes the character, escaped if needed
+func (h *htmlWriter) char(r rune) {
+	switch r {
+	case '<':
+		h.w.WriteString("&lt;")
+	case '>':
+		h.w.WriteString("&gt;")
+	case '&':
+		h.w.WriteString("&amp;")
+	case '"':
+		h.w.WriteString("&#34;")
+	case '\'':
+		h.w.WriteString("&#39;")
+	default:
+		h.w.WriteRune(r)
+	}
+}
+
+// css returns the colour as #rrggbb, after quantising and mapping it onto the palette for the depth. Returns an
+// empty string for nil, or if there's no colour
+func (o EncodeOpts) css(c *colour.Colour256) string {
+	m := o.mapped(c)
+	if m == nil {
+		return ""
+	}
+	return "#" + strings.TrimPrefix(m.Hex(), "0x")
+}
+
+// mapped returns the colour as it ends up in the output: quantised, and mapped onto the palette for the depth.
+// Returns nil for nil, or if there's no colour
+func (o EncodeOpts) mapped(c *colour.Colour256) *colour.Colour256 {
+	if c == nil || o.Depth == colour.NoColour {
+		return nil
+	}
+	q := o.quantise(*c)
+	switch o.Depth {
+	case colour.Colours256:
+		_, q = q.Nearest(colour.Palette256)
+	case colour.Colours16:
+		_, q = q.Nearest(colour.Palette16)
+	}
+	return &q
+}
@@ -0,0 +1,154 @@
+package convert
+
+import (
+	"errors"
+	"math"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+// Luminance the model used to determine how bright a pixel is
+type Luminance uint32
+
+const (
+	// AverageLuminance simply averages the channels (r+g+b)/3, which is what we used to do
+	AverageLuminance Luminance = iota
+	// Rec601Luminance uses the Rec.601 (SD TV) weights on the gamma-encoded values
+	Rec601Luminance
+	// Rec709Luminance uses the Rec.709 (sRGB/HD) weights on the gamma-encoded values
+	Rec709Luminance
+	// LinearLuminance decodes sRGB gamma, applies the Rec.709 weights in linear light, and re-encodes the result
+	// this is the most accurate, but also the slowest
+	LinearLuminance
+)
+
+// Adjustments are applied to the brightness (or colour channels) of a pixel before looking up the character.
+// The zero value means no adjustments are made
+type Adjustments struct {
+	// Gamma is applied as v^(1/Gamma), values > 1 brighten the mid-tones, 0 and 1 do nothing
+	Gamma float64
+	// Brightness is added to the value (-1 to 1), 0 does nothing
+	Brightness float64
+	// Contrast scales the value around the mid-point, 0 and 1 do nothing
+	Contrast float64
+}
+
+var (
+	ErrInvalidLuminance = errors.New("specified luminance model not supported")
+
+	lumStr = map[Luminance]string{
+		AverageLuminance: "avg",
+		Rec601Luminance:  "rec601",
+		Rec709Luminance:  "rec709",
+		LinearLuminance:  "linear",
+	}
+
+	// LuminanceModels all supported models, in the order we list them
+	LuminanceModels = []Luminance{
+		AverageLuminance,
+		Rec601Luminance,
+		Rec709Luminance,
+		LinearLuminance,
+	}
+)
+
+// ParseLuminance returns the luminance model for a given name (as returned by String)
+func ParseLuminance(name string) (Luminance, error) {
+	for l, s := range lumStr {
+		if s == name {
+			return l, nil
+		}
+	}
+	return AverageLuminance, ErrInvalidLuminance
+}
+
+// Of returns the luminance of the RGB values (0 - 0xffff per channel, as returned by color.Color.RGBA())
+// as a value between 0 and 1
+func (l Luminance) Of(r, g, b uint32) float64 {
+	rf, gf, bf := float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff
+	switch l {
+	case Rec601Luminance:
+		return 0.299*rf + 0.587*gf + 0.114*bf
+	case Rec709Luminance:
+		return 0.2126*rf + 0.7152*gf + 0.0722*bf
+	case LinearLuminance:
+		y := 0.2126*toLinear(rf) + 0.7152*toLinear(gf) + 0.0722*toLinear(bf)
+		return fromLinear(y)
+	}
+	return (rf + gf + bf) / 3
+}
+
+// String returns the luminance model name
+func (l Luminance) String() string {
+	s, ok := lumStr[l]
+	if !ok {
+		return ""
+	}
+	return s
+}
+
+// Apply adjusts a value in the 0-1 range, the result is clamped to that same range
+func (a Adjustments) Apply(v float64) float64 {
+	if a.Gamma > 0 && a.Gamma != 1 {
+		v = math.Pow(v, 1/a.Gamma)
+	}
+	if a.Contrast > 0 && a.Contrast != 1 {
+		v = (v-0.5)*a.Contrast + 0.5
+	}
+	return clamp(v + a.Brightness)
+}
+
+// IsZero returns true if applying the adjustments doesn't change anything
+func (a Adjustments) IsZero() bool {
+	return (a.Gamma <= 0 || a.Gamma == 1) && (a.Contrast <= 0 || a.Contrast == 1) && a.Brightness == 0
+}
+
+// Colour applies the adjustments to each channel of the given colour
+func (a Adjustments) Colour(c *colour.Colour256) *colour.Colour256 {
+	if c == nil || a.IsZero() {
+		return c
+	}
+	adjusted := a.adjust(*c)
+	return &adjusted
+}
+
+func (a Adjustments) adjust(c colour.Colour256) colour.Colour256 {
+	if a.IsZero() {
+		return c
+	}
+	return colour.Colour256{
+		R: a.channel(c.R),
+		G: a.channel(c.G),
+		B: a.channel(c.B),
+	}
+}
+
+func (a Adjustments) channel(v uint8) uint8 {
+	return uint8(math.Round(a.Apply(float64(v)/255) * 255))
+}
+
+// toLinear decodes an sRGB encoded value
+func toLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// fromLinear applies the sRGB gamma
+func fromLinear(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+func clamp(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,83 @@
+package convert
+
+import (
+	"image"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+// pixels reads the pixels of an image. img.At returns an interface value, so every pixel means an allocation
+// and a couple of indirect calls. That adds up quickly (think webcam frame rates), so for *image.RGBA, which is
+// what the scale package returns, we read the Pix slice directly.
+// Coordinates are relative to Bounds().Min, so the rest of the package can treat every image as if it starts
+// at 0, 0 (which isn't the case for sub-images, or some decoded images)
+// Partially transparent pixels are blended with the background colour, see composite
+type pixels struct {
+	img  image.Image
+	rgba *image.RGBA
+	min  image.Point
+	size image.Point
+	bg   *colour.Colour256
+}
+
+func newPixels(img image.Image, bg *colour.Colour256) pixels {
+	b := img.Bounds()
+	p := pixels{
+		img:  img,
+		min:  b.Min,
+		size: b.Size(),
+		bg:   bg,
+	}
+	p.rgba, _ = img.(*image.RGBA)
+	return p
+}
+
+// RGBA returns the channels in the 0-0xffff range, like color.Color.RGBA does, after compositing
+func (p pixels) RGBA(x, y int) (r, g, b, a uint32) {
+	return p.composite(p.raw(x+p.min.X, y+p.min.Y))
+}
+
+// raw returns the alpha-premultiplied values of the pixel at x, y (absolute coordinates)
+func (p pixels) raw(x, y int) (r, g, b, a uint32) {
+	if p.rgba == nil {
+		return p.img.At(x, y).RGBA()
+	}
+	if !(image.Point{X: x, Y: y}).In(p.rgba.Rect) {
+		return 0, 0, 0, 0
+	}
+	i := p.rgba.PixOffset(x, y)
+	s := p.rgba.Pix[i : i+4 : i+4]
+	r, g, b, a = uint32(s[0]), uint32(s[1]), uint32(s[2]), uint32(s[3])
+	return r | r<<8, g | g<<8, b | b<<8, a | a<<8
+}
+
+// composite blends a partially transparent pixel with the background colour, so the result is opaque. Without a
+// background, we don't know what's behind the pixel (it's up to the terminal), fully transparent pixels stay
+// transparent, the others are shown in their own colour. The values are premultiplied, so we have to divide by
+// alpha, otherwise anti-aliased edges end up looking a lot darker than they should
+func (p pixels) composite(r, g, b, a uint32) (uint32, uint32, uint32, uint32) {
+	switch {
+	case a == 0xffff:
+		return r, g, b, a
+	case p.bg != nil:
+		// premultiplied, so all we need to do is add the part of the background that shows through
+		t := 0xffff - a
+		return r + uint32(p.bg.R)*0x101*t/0xffff, g + uint32(p.bg.G)*0x101*t/0xffff, b + uint32(p.bg.B)*0x101*t/0xffff, 0xffff
+	case a == 0:
+		return 0, 0, 0, 0
+	}
+	return r * 0xffff / a, g * 0xffff / a, b * 0xffff / a, a
+}
+
+// rgb returns the colour of the pixel, false if it's transparent
+func (p pixels) rgb(x, y int) (colour.Colour256, bool) {
+	return toColour(p.RGBA(x, y))
+}
+
+// toColour does the same as colour.FromRGBA, without allocating the colour
+func toColour(r, g, b, a uint32) (colour.Colour256, bool) {
+	if a == 0 {
+		return colour.Colour256{}, false
+	}
+	return colour.Colour256{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8)}, true
+}
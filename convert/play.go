@@ -0,0 +1,62 @@
+package convert
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+const (
+	hideCursor  = "\033[?25l"
+	showCursor  = "\033[?25h"
+	clearScreen = "\033[2J"
+	cursorHome  = "\033[H"
+
+	// DefaultFrameDelay is used for frames with a delay of 10ms or less. A lot of GIFs have a delay of 0, browsers
+	// show those frames for 100ms, so that's what the GIFs are made to look right with
+	DefaultFrameDelay = 100 * time.Millisecond
+)
+
+// Frame is a converted frame of an animation (as returned by the ImgTo functions), and how long it's shown for
+type Frame struct {
+	Output string
+	Delay  time.Duration
+}
+
+// Play shows the frames in the terminal, drawing each frame over the previous one. loopCount works like it does
+// in image/gif: 0 loops forever, -1 plays the frames once, n plays them n+1 times. Play returns once all loops are
+// done, or when ctx is cancelled (e.g. Ctrl-C, see signal.NotifyContext), returning ctx.Err(). Either way, the
+// cursor is shown again, and the colours are reset
+func Play(ctx context.Context, w io.Writer, frames []Frame, loopCount int) error {
+	if len(frames) == 0 {
+		return nil
+	}
+	if _, err := io.WriteString(w, hideCursor+clearScreen); err != nil {
+		return err
+	}
+	defer io.WriteString(w, colour.ResetColour+showCursor+"\n")
+	for loop := 0; loopCount <= 0 || loop <= loopCount; loop++ {
+		for _, f := range frames {
+			if _, err := io.WriteString(w, cursorHome+f.Output); err != nil {
+				return err
+			}
+			delay := f.Delay
+			if delay <= 10*time.Millisecond {
+				delay = DefaultFrameDelay
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if loopCount < 0 {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package convert
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/EVODelavega/asciify/colour"
+)
+
+// rasterFontSize is the size (in pixels) of the font used to draw PNG, JPEG and GIF output
+const rasterFontSize = 14
+
+var (
+	gifPaletteOnce sync.Once
+	gifPalette     color.Palette
+
+	// the colours used if a cell doesn't have any, same as HTML and SVG output
+	rasterBG = color.RGBA{A: 0xff}
+	rasterFG = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+// rasterWriter draws the rows into an image using the bundled monospace font, and encodes it in the format
+// (PNG, JPEG or GIF) once all rows are drawn. If w is nil, the image is only drawn. Block elements are drawn as
+// rectangles, so they fill the cell, like they do in a terminal (Go Mono doesn't have the quadrants and sextants
+// anyway)
+type rasterWriter struct {
+	w      textWriter
+	opts   EncodeOpts
+	format Format
+	img    *image.RGBA
+	face   font.Face
+	// cell is the size of a character, baseline the offset of the baseline from the top of the cell
+	cell     image.Point
+	baseline int
+	// y is the row we're on
+	y int
+}
+
+// Rasterise draws the grid as an image, using the bundled monospace font. Cells without a colour are drawn
+// white on black, colours are quantised and mapped onto the palette for the depth, as they would be in the output
+func (g Grid) Rasterise(opts EncodeOpts) *image.RGBA {
+	r := rasterWriter{
+		opts: opts,
+	}
+	cols := 0
+	if len(g) > 0 {
+		cols = len(g[0])
+	}
+	r.begin(len(g), cols)
+	for _, row := range g {
+		r.row(row)
+	}
+	r.end()
+	return r.img
+}
+
+func (r *rasterWriter) begin(rows, cols int) {
+	r.y = 0
+	r.face = monoFace(rasterFontSize)
+	metrics := r.face.Metrics()
+	adv, _ := r.face.GlyphAdvance('M') // monospace, so all characters have the same advance
+	r.cell = image.Pt(adv.Ceil(), (metrics.Ascent + metrics.Descent).Ceil())
+	r.baseline = metrics.Ascent.Ceil()
+	r.img = image.NewRGBA(image.Rect(0, 0, cols*r.cell.X, rows*r.cell.Y))
+	draw.Draw(r.img, r.img.Rect, image.NewUniform(rasterBG), image.Point{}, draw.Src)
+}
+
+func (r *rasterWriter) row(row []Cell) {
+	d := font.Drawer{
+		Dst:  r.img,
+		Face: r.face,
+	}
+	top := r.y * r.cell.Y
+	for x, c := range row {
+		cell := image.Rect(x*r.cell.X, top, (x+1)*r.cell.X, top+r.cell.Y)
+		if bg := r.opts.mapped(c.BG); bg != nil {
+			r.fill(cell, *bg)
+		}
+		fg := rasterFG
+		if m := r.opts.mapped(c.FG); m != nil {
+			fg = color.RGBA{R: m.R, G: m.G, B: m.B, A: 0xff}
+		}
+		if mask, h, ok := blockMask(c.Char); ok {
+			r.block(cell, mask, h, fg)
+			continue
+		}
+		d.Src = image.NewUniform(fg)
+		d.Dot = fixed.P(cell.Min.X, top+r.baseline)
+		d.DrawString(string(c.Char))
+	}
+	r.y++
+}
+
+// block draws a block element, each row of the pattern is 2 pixels wide (see blockMask)
+func (r *rasterWriter) block(cell image.Rectangle, mask, h int, fg color.RGBA) {
+	mid := cell.Min.X + cell.Dx()/2
+	for by := 0; by < h; by++ {
+		// spread the rows evenly, the cell height isn't always a multiple of h
+		y0, y1 := cell.Min.Y+by*cell.Dy()/h, cell.Min.Y+(by+1)*cell.Dy()/h
+		bits := (mask >> (by * 2)) & 3
+		if bits&1 != 0 {
+			draw.Draw(r.img, image.Rect(cell.Min.X, y0, mid, y1), image.NewUniform(fg), image.Point{}, draw.Src)
+		}
+		if bits&2 != 0 {
+			draw.Draw(r.img, image.Rect(mid, y0, cell.Max.X, y1), image.NewUniform(fg), image.Point{}, draw.Src)
+		}
+	}
+}
+
+func (r *rasterWriter) fill(rect image.Rectangle, c colour.Colour256) {
+	draw.Draw(r.img, rect, image.NewUniform(color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}), image.Point{}, draw.Src)
+}
+
+// rows are positioned explicitly, nothing to do here
+func (r *rasterWriter) newline() {}
+
+// end encodes the image, errors are returned by the writer when flushing (see textWriter)
+func (r *rasterWriter) end() {
+	r.face.Close()
+	if r.w == nil {
+		return
+	}
+	switch r.format {
+	case JPEGFormat:
+		jpeg.Encode(r.w, r.img, &jpeg.Options{
+			Quality: 100, // text gets blurry quickly
+		})
+	case GIFFormat:
+		gif.Encode(r.w, paletted(r.img), nil)
+	default:
+		png.Encode(r.w, r.img)
+	}
+}
+
+// paletted maps the image onto the xterm 256 colour palette, a GIF can't have more colours than that. Text
+// doesn't dither well, so each pixel gets the closest colour
+func paletted(img *image.RGBA) *image.Paletted {
+	gifPaletteOnce.Do(func() {
+		gifPalette = make(color.Palette, 256)
+		for i := range gifPalette {
+			c := colour.Indexed(uint8(i))
+			gifPalette[i] = color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}
+		}
+	})
+	dst := image.NewPaletted(img.Rect, gifPalette)
+	// the output has few distinct colours (mostly the antialiased edges of the characters), no need to look
+	// through the palette for every pixel
+	seen := map[color.RGBA]uint8{}
+	for i := 0; i < len(img.Pix); i += 4 {
+		c := color.RGBA{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2], A: img.Pix[i+3]}
+		idx, ok := seen[c]
+		if !ok {
+			idx = uint8(gifPalette.Index(c))
+			seen[c] = idx
+		}
+		dst.Pix[i/4] = idx
+	}
+	return dst
+}
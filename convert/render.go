@@ -0,0 +1,218 @@
+package convert
+
+import (
+	"bufio"
+	"image"
+	"image/gif"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/EVODelavega/asciify/scale"
+)
+
+// layout describes the output of a render mode: the number of rows, the number of characters per row,
+// and the func that populates a row. fill is called concurrently (see forEachRow), once for each row
+type layout struct {
+	rows, cols int
+	fill       func(row []Cell, y int)
+}
+
+// Renderer writes the output to an io.Writer instead of returning a string. Rows are written in order as soon
+// as they're done, so the output starts before the whole image is converted, and the output never has to be
+// in memory in its entirety. Each row, including the last one, ends with a newline. The methods match the
+// ImgTo functions, and use the same options
+type Renderer struct {
+	w    *bufio.Writer
+	opts ConvertOpts
+	// raster, if set, is used instead of the writer for the format, the output is only drawn (see GIF)
+	raster *rasterWriter
+}
+
+// NewRenderer returns a renderer writing to w (buffered) using the given options
+func NewRenderer(w io.Writer, opts ConvertOpts) *Renderer {
+	return &Renderer{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+	}
+}
+
+// Preview streams the output of ImgToPreview
+func (r *Renderer) Preview(img image.Image, single bool) error {
+	return r.stream(previewLayout(img, single, r.opts))
+}
+
+// ASCII streams the output of ImgToASCII
+func (r *Renderer) ASCII(img image.Image) error {
+	return r.stream(asciiLayout(img, r.opts, false))
+}
+
+// ASCIIColoured streams the output of ImgToASCIIColoured
+func (r *Renderer) ASCIIColoured(img image.Image) error {
+	return r.stream(asciiLayout(img, r.opts, true))
+}
+
+// Braille streams the output of ImgToBraille
+func (r *Renderer) Braille(img image.Image) error {
+	return r.stream(brailleLayout(img, r.opts, false))
+}
+
+// BrailleColoured streams the output of ImgToBrailleColoured
+func (r *Renderer) BrailleColoured(img image.Image) error {
+	return r.stream(brailleLayout(img, r.opts, true))
+}
+
+// Shape streams the output of ImgToShape
+func (r *Renderer) Shape(img image.Image) error {
+	return r.stream(shapeLayout(img, r.opts))
+}
+
+// Edges streams the output of ImgToEdges
+func (r *Renderer) Edges(img image.Image) error {
+	return r.stream(edgeLayout(img, r.opts))
+}
+
+// HalfBlock streams the output of ImgToHalfBlock
+func (r *Renderer) HalfBlock(img image.Image) error {
+	return r.stream(halfBlockLayout(img, r.opts))
+}
+
+// Quadrant streams the output of ImgToQuadrant
+func (r *Renderer) Quadrant(img image.Image) error {
+	return r.stream(blockLayout(img, r.opts, 2, quadrantRune))
+}
+
+// Sextant streams the output of ImgToSextant
+func (r *Renderer) Sextant(img image.Image) error {
+	return r.stream(blockLayout(img, r.opts, 3, sextantRune))
+}
+
+// Grid writes a grid, like one returned by ParseANSI, in the output format of the renderer. This is how saved
+// output can be converted to another format, or a different colour depth. All rows must be the same length
+func (r *Renderer) Grid(g Grid) error {
+	return r.stream(gridLayout(g))
+}
+
+// GIF renders every frame of the animation using render (a Renderer method, like (*Renderer).ASCII), draws the
+// output as an image (see Grid.Rasterise), and writes the frames as an animated GIF with the same delays and loop
+// count. The output is a GIF regardless of the format of the renderer. Frames are mapped onto the xterm 256 colour
+// palette, and should all be the same size
+func (r *Renderer) GIF(anim *scale.Animation, render func(r *Renderer, img image.Image) error) error {
+	raster := &rasterWriter{
+		opts: r.opts.encodeOpts(),
+	}
+	fr := &Renderer{
+		opts:   r.opts,
+		raster: raster,
+	}
+	g := gif.GIF{
+		LoopCount: anim.LoopCount,
+	}
+	for _, f := range anim.Frames {
+		if err := render(fr, f.Image); err != nil {
+			return err
+		}
+		g.Image = append(g.Image, paletted(raster.img))
+		// the delay is in 100ths of a second
+		g.Delay = append(g.Delay, int(f.Delay/(10*time.Millisecond)))
+	}
+	if err := gif.EncodeAll(r.w, &g); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+// stream populates the rows, and writes them in order as they complete. Each row is allocated separately, so it
+// can be freed once it's written
+func (r *Renderer) stream(l layout) error {
+	grid := make(Grid, l.rows)
+	done := make([]chan struct{}, l.rows)
+	for y := range done {
+		done[y] = make(chan struct{})
+	}
+	go forEachRow(l.rows, r.opts.Workers, func(y int) {
+		row := make([]Cell, l.cols)
+		l.fill(row, y)
+		grid[y] = row
+		close(done[y])
+	})
+	var rw rowWriter = r.raster
+	if r.raster == nil {
+		rw = r.opts.rowWriter(r.w)
+	}
+	rw.begin(l.rows, l.cols)
+	for y := range grid {
+		<-done[y]
+		rw.row(grid[y])
+		rw.newline()
+		// this row is written, no need to hang on to it
+		grid[y] = nil
+	}
+	rw.end()
+	r.opts.recordStats(rw)
+	if r.raster != nil {
+		// nothing was written
+		return nil
+	}
+	return r.w.Flush()
+}
+
+// render populates all rows, and returns the encoded grid
+func (o ConvertOpts) render(l layout) string {
+	grid := l.alloc()
+	forEachRow(l.rows, o.Workers, func(y int) {
+		l.fill(grid[y], y)
+	})
+	return o.encode(grid)
+}
+
+// gridLayout copies the rows of an existing grid
+func gridLayout(g Grid) layout {
+	cols := 0
+	if len(g) > 0 {
+		cols = len(g[0])
+	}
+	return layout{
+		rows: len(g),
+		cols: cols,
+		fill: func(row []Cell, y int) {
+			copy(row, g[y])
+		},
+	}
+}
+
+// alloc returns an empty grid, all rows share a single backing array (the whole grid is needed until the end anyway)
+func (l layout) alloc() Grid {
+	grid := make(Grid, l.rows)
+	cells := make([]Cell, l.rows*l.cols)
+	for y := range grid {
+		grid[y] = cells[y*l.cols : (y+1)*l.cols : (y+1)*l.cols]
+	}
+	return grid
+}
+
+// forEachRow calls fn for rows 0 through n-1 using a bounded number of workers (GOMAXPROCS if workers <= 0).
+// Workers pick up the rows in order, so the first rows are done first, which is what we want when streaming.
+// Returns once all rows are done
+func forEachRow(n, workers int, fn func(y int)) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	next := int64(-1)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for y := int(atomic.AddInt64(&next, 1)); y < n; y = int(atomic.AddInt64(&next, 1)) {
+				fn(y)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,79 @@
+package convert
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// benchWidth and benchHeight are the size of a typical terminal sized frame (asciicam -w 160 -h 80)
+const (
+	benchWidth  = 160
+	benchHeight = 80
+)
+
+var benchWorkers = []int{1, 2, 4, 0}
+
+// benchFrame returns a frame with a gradient, so every row has plenty of different characters and colours
+func benchFrame() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, benchWidth, benchHeight))
+	for y := 0; y < benchHeight; y++ {
+		for x := 0; x < benchWidth; x++ {
+			img.Set(x, y, color.RGBA{
+				R: uint8(x * 255 / benchWidth),
+				G: uint8(y * 255 / benchHeight),
+				B: uint8((x + y) * 3),
+				A: 0xff,
+			})
+		}
+	}
+	return img
+}
+
+// benchImages returns the frame as *image.RGBA (read straight from Pix) and *image.NRGBA (read using At)
+func benchImages() map[string]image.Image {
+	rgba := benchFrame()
+	nrgba := image.NewNRGBA(rgba.Rect)
+	for y := 0; y < benchHeight; y++ {
+		for x := 0; x < benchWidth; x++ {
+			nrgba.Set(x, y, rgba.At(x, y))
+		}
+	}
+	return map[string]image.Image{
+		"RGBA":  rgba,
+		"NRGBA": nrgba,
+	}
+}
+
+func benchConvert(b *testing.B, fn func(image.Image, ConvertOpts) string) {
+	images := benchImages()
+	for _, name := range []string{"RGBA", "NRGBA"} {
+		img := images[name]
+		for _, w := range benchWorkers {
+			b.Run(fmt.Sprintf("%s/workers=%d", name, w), func(b *testing.B) {
+				opts := ConvertOpts{
+					Workers: w,
+				}
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					fn(img, opts)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkImgToASCII(b *testing.B) {
+	benchConvert(b, ImgToASCII)
+}
+
+func BenchmarkImgToASCIIColoured(b *testing.B) {
+	benchConvert(b, ImgToASCIIColoured)
+}
+
+func BenchmarkImgToPreview(b *testing.B) {
+	benchConvert(b, func(img image.Image, opts ConvertOpts) string {
+		return ImgToPreview(img, false, opts)
+	})
+}
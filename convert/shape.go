@@ -0,0 +1,176 @@
+package convert
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// glyphSize is the font size (pixels) we rasterise glyphs at before downsampling them to the cell size
+	glyphSize = 32
+	// shapeChars are the characters we try to match by default: all printable ASCII characters
+	shapeChars = " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+)
+
+var (
+	// DefaultShapeCell is the number of pixels each character covers in shape mode
+	DefaultShapeCell = image.Pt(4, 8)
+
+	// masks caches the glyph coverage per set of characters and cell size
+	masks sync.Map
+)
+
+// glyphMask is the coverage of a glyph (0 is empty, 1 is fully covered) downsampled to the cell size
+type glyphMask struct {
+	char rune
+	cov  []float64
+}
+
+// ImgToShape matches the shape of characters to blocks of pixels, instead of just using the brightness. Each
+// character is rasterised using the bundled monospace font, and for each block of ShapeCell pixels, we pick
+// the character with the coverage closest (smallest mean squared error) to the brightness of the pixels.
+// This means lines become /, \, | or _ and so on. If Charset is nil, all printable ASCII characters are used
+func ImgToShape(img image.Image, opts ConvertOpts) string {
+	return opts.render(shapeLayout(img, opts))
+}
+
+func shapeLayout(img image.Image, opts ConvertOpts) layout {
+	px := newPixels(img, opts.Background)
+	size := px.size
+	cell := opts.shapeCell()
+	chars := shapeChars
+	if opts.Charset != nil {
+		chars = opts.Charset.String()
+	}
+	glyphs := glyphMasks(chars, cell)
+	return layout{
+		rows: (size.Y + cell.Y - 1) / cell.Y,
+		cols: (size.X + cell.X - 1) / cell.X,
+		fill: func(row []Cell, y int) {
+			shapeRow(row, px, y*cell.Y, cell, glyphs, opts)
+		},
+	}
+}
+
+func shapeRow(row []Cell, px pixels, y int, cell image.Point, glyphs []glyphMask, opts ConvertOpts) {
+	size := px.size
+	block := make([]float64, cell.X*cell.Y)
+	for cx := range row {
+		for dy := 0; dy < cell.Y; dy++ {
+			for dx := 0; dx < cell.X; dx++ {
+				x := cx*cell.X + dx
+				if opts.Invert {
+					x = size.X - x - 1
+				}
+				// pixels outside of the image, or transparent ones, are "no ink"
+				v := 0.0
+				if x >= 0 && x < size.X && y+dy < size.Y {
+					if r, g, b, a := px.RGBA(x, y+dy); a != 0 {
+						v = opts.Apply(opts.Luminance.Of(r, g, b))
+						// light pixels are shown as dense characters, unless Negative is set
+						if opts.Negative {
+							v = 1 - v
+						}
+					}
+				}
+				block[dy*cell.X+dx] = v
+			}
+		}
+		row[cx] = Cell{Char: bestGlyph(block, glyphs)}
+	}
+}
+
+// bestGlyph returns the character with the smallest squared error
+func bestGlyph(block []float64, glyphs []glyphMask) rune {
+	best, char := math.Inf(1), ' '
+	for _, g := range glyphs {
+		e := 0.0
+		for i, v := range block {
+			d := v - g.cov[i]
+			e += d * d
+		}
+		if e < best {
+			best, char = e, g.char
+		}
+	}
+	return char
+}
+
+// glyphMasks returns the (cached) coverage of all characters
+func glyphMasks(chars string, cell image.Point) []glyphMask {
+	key := fmt.Sprintf("%dx%d:%s", cell.X, cell.Y, chars)
+	if m, ok := masks.Load(key); ok {
+		return m.([]glyphMask)
+	}
+	face := monoFace(glyphSize)
+	defer face.Close()
+	metrics := face.Metrics()
+	adv, _ := face.GlyphAdvance('M') // monospace, so all characters have the same advance
+	w, h := adv.Ceil(), (metrics.Ascent + metrics.Descent).Ceil()
+	dst := image.NewAlpha(image.Rect(0, 0, w, h))
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.Opaque,
+		Face: face,
+	}
+	glyphs := make([]glyphMask, 0, len(chars))
+	top := 0.0
+	for _, c := range chars {
+		draw.Draw(dst, dst.Rect, image.Transparent, image.Point{}, draw.Src)
+		d.Dot = fixed.Point26_6{Y: metrics.Ascent}
+		d.DrawString(string(c))
+		g := glyphMask{
+			char: c,
+			cov:  downsample(dst, cell),
+		}
+		for _, v := range g.cov {
+			top = math.Max(top, v)
+		}
+		glyphs = append(glyphs, g)
+	}
+	// strokes are thin, even the densest part of a glyph doesn't cover a cell entirely, stretch
+	// the coverage so the densest part counts as fully covered
+	if top > 0 {
+		for _, g := range glyphs {
+			for i := range g.cov {
+				g.cov[i] /= top
+			}
+		}
+	}
+	masks.Store(key, glyphs)
+	return glyphs
+}
+
+// downsample averages the alpha values of src into a cell.X by cell.Y grid
+func downsample(src *image.Alpha, cell image.Point) []float64 {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	sum := make([]float64, cell.X*cell.Y)
+	n := make([]int, cell.X*cell.Y)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := (y*cell.Y/h)*cell.X + x*cell.X/w
+			sum[i] += float64(src.AlphaAt(x, y).A) / 0xff
+			n[i]++
+		}
+	}
+	for i := range sum {
+		if n[i] > 0 {
+			sum[i] /= float64(n[i])
+		}
+	}
+	return sum
+}
+
+// shapeCell returns the cell size to use, falls back to the default
+func (o ConvertOpts) shapeCell() image.Point {
+	if o.ShapeCell.X <= 0 || o.ShapeCell.Y <= 0 {
+		return DefaultShapeCell
+	}
+	return o.ShapeCell
+}
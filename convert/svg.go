@@ -0,0 +1,153 @@
+package convert
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// the size of a cell in SVG output. Monospace fonts are ~0.6em wide, the lines are twice as high as
+	// a character is wide, like they are in a terminal
+	svgFontSize   = 10
+	svgCellWidth  = 6
+	svgLineHeight = 12
+	// svgBaseline is the offset of the baseline from the top of the line
+	svgBaseline = 9
+)
+
+// svgWriter writes the rows as an SVG image. Every character is positioned on the grid explicitly (the x
+// attribute of a tspan takes a position per character), so the result doesn't depend on the font being exactly
+// monospace. Background colours are drawn as rectangles, and so are block elements (half blocks, quadrants,
+// sextants), so they line up perfectly, and don't depend on the font supporting them
+type svgWriter struct {
+	w    textWriter
+	opts EncodeOpts
+	// y is the row we're on
+	y int
+}
+
+func (s *svgWriter) begin(rows, cols int) {
+	w, h := strconv.Itoa(cols*svgCellWidth), strconv.Itoa(rows*svgLineHeight)
+	s.w.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="` + w + `" height="` + h + `" viewBox="0 0 ` + w + " " + h + `">` + "\n")
+	s.w.WriteString(`<rect width="100%" height="100%" fill="#000"/>` + "\n")
+	s.w.WriteString(`<g font-family="monospace" font-size="` + strconv.Itoa(svgFontSize) + `" fill="#fff">` + "\n")
+}
+
+func (s *svgWriter) row(row []Cell) {
+	top := s.y * svgLineHeight
+	s.backgrounds(row, top)
+	s.blocks(row, top)
+	s.text(row, top)
+	s.w.WriteString("\n")
+	s.y++
+}
+
+// backgrounds draws the background colours, a single rectangle per run of cells with the same colour
+func (s *svgWriter) backgrounds(row []Cell, top int) {
+	start, fill := 0, ""
+	for x := 0; x <= len(row); x++ {
+		bg := ""
+		if x < len(row) {
+			bg = s.opts.css(row[x].BG)
+		}
+		if bg == fill {
+			continue
+		}
+		if fill != "" {
+			s.rect(start*svgCellWidth, top, (x-start)*svgCellWidth, svgLineHeight, fill)
+		}
+		start, fill = x, bg
+	}
+}
+
+// blocks draws the block elements, each row of the pattern (2 pixels wide) is a rectangle, or 2 narrow ones
+func (s *svgWriter) blocks(row []Cell, top int) {
+	for x, c := range row {
+		mask, h, ok := blockMask(c.Char)
+		if !ok || mask == 0 {
+			continue
+		}
+		fill := s.opts.css(c.FG)
+		if fill == "" {
+			fill = "#fff"
+		}
+		left, ph := x*svgCellWidth, svgLineHeight/h
+		for by := 0; by < h; by++ {
+			switch bits := (mask >> (by * 2)) & 3; bits {
+			case 0:
+			case 3:
+				s.rect(left, top+by*ph, svgCellWidth, ph, fill)
+			default:
+				// 1 is the left pixel, 2 the right one
+				s.rect(left+(bits-1)*svgCellWidth/2, top+by*ph, svgCellWidth/2, ph, fill)
+			}
+		}
+	}
+}
+
+// text writes all other characters, characters in the same colour share a tspan. A space is an empty block
+// element, so those are skipped
+func (s *svgWriter) text(row []Cell, top int) {
+	var (
+		fill string
+		xs   []string
+		text strings.Builder
+	)
+	started := false
+	flush := func() {
+		if len(xs) == 0 {
+			return
+		}
+		if !started {
+			s.w.WriteString(`<text y="` + strconv.Itoa(top+svgBaseline) + `">`)
+			started = true
+		}
+		s.w.WriteString(`<tspan x="` + strings.Join(xs, " ") + `"`)
+		if fill != "" {
+			s.w.WriteString(` fill="` + fill + `"`)
+		}
+		s.w.WriteString(">" + text.String() + "</tspan>")
+		xs = xs[:0]
+		text.Reset()
+	}
+	for x, c := range row {
+		if _, _, ok := blockMask(c.Char); ok {
+			continue
+		}
+		if f := s.opts.css(c.FG); f != fill {
+			flush()
+			fill = f
+		}
+		xs = append(xs, strconv.Itoa(x*svgCellWidth))
+		svgChar(&text, c.Char)
+	}
+	flush()
+	if started {
+		s.w.WriteString("</text>")
+	}
+}
+
+func (s *svgWriter) rect(x, y, w, h int, fill string) {
+	s.w.WriteString(`<rect x="` + strconv.Itoa(x) + `" y="` + strconv.Itoa(y) + `" width="` + strconv.Itoa(w) + `" height="` + strconv.Itoa(h) + `" fill="` + fill + `"/>`)
+}
+
+// rows are positioned explicitly, so there's no need for newlines (row adds one to keep the output readable)
+func (s *svgWriter) newline() {}
+
+func (s *svgWriter) end() {
+	s.w.WriteString("</g>\n</svg>\n")
+}
+
+// svgChar writes the character, escaped if needed
+func svgChar(sb *strings.Builder, r rune) {
+	switch r {
+	case '<':
+		sb.WriteString("&lt;")
+	case '>':
+		sb.WriteString("&gt;")
+	case '&':
+		sb.WriteString("&amp;")
+	default:
+		sb.WriteRune(r)
+	}
+}
@@ -2,8 +2,10 @@ module github.com/EVODelavega/asciify
 
 go 1.18
 
+require golang.org/x/image v0.1.0
+
 require (
 	github.com/vladimirvivien/go4vl v0.0.5 // indirect
-	golang.org/x/image v0.1.0 // indirect
 	golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
+	golang.org/x/text v0.4.0 // indirect
 )
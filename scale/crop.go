@@ -0,0 +1,43 @@
+package scale
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	ErrEmptyCrop   = errors.New("crop area is outside of the image")
+	ErrInvalidCrop = errors.New("crop area should be specified as x,y,width,height")
+)
+
+// Crop returns the part of the image inside r. The rectangle is relative to the top left corner of the image, so it
+// works the same for images that don't start at 0, 0. The result is a sub-image if the image type supports it,
+// which means its bounds don't start at 0, 0 either. A rectangle partially outside of the image is clipped
+func Crop(img image.Image, r image.Rectangle) (image.Image, error) {
+	b := img.Bounds()
+	r = r.Add(b.Min).Intersect(b)
+	if r.Empty() {
+		return nil, ErrEmptyCrop
+	}
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r), nil
+	}
+	// no sub-images (image.Uniform, for example), copy the pixels instead
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Rect, img, r.Min, draw.Src)
+	return dst, nil
+}
+
+// ParseRect parses a crop area in the x,y,width,height format (as used by the -crop flag)
+func ParseRect(s string) (image.Rectangle, error) {
+	var x, y, w, h int
+	if n, err := fmt.Sscanf(s, "%d,%d,%d,%d", &x, &y, &w, &h); err != nil || n != 4 || w <= 0 || h <= 0 {
+		return image.Rectangle{}, ErrInvalidCrop
+	}
+	return image.Rect(x, y, x+w, y+h), nil
+}
@@ -0,0 +1,162 @@
+package scale
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCrop(t *testing.T) {
+	img := image.NewRGBA(image.Rect(10, 20, 50, 40))
+	img.Set(15, 25, color.White)
+	tests := []struct {
+		name string
+		r    image.Rectangle
+		want image.Rectangle
+	}{
+		{
+			name: "inside",
+			r:    image.Rect(5, 5, 15, 10),
+			want: image.Rect(15, 25, 25, 30),
+		},
+		{
+			name: "entire image",
+			r:    image.Rect(0, 0, 40, 20),
+			want: img.Rect,
+		},
+		{
+			name: "clipped bottom right",
+			r:    image.Rect(30, 10, 100, 100),
+			want: image.Rect(40, 30, 50, 40),
+		},
+		{
+			name: "clipped top left",
+			r:    image.Rect(-5, -5, 5, 5),
+			want: image.Rect(10, 20, 15, 25),
+		},
+	}
+	for _, tt := range tests {
+		got, err := Crop(img, tt.r)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if got.Bounds() != tt.want {
+			t.Errorf("%s: expected bounds %v, got %v", tt.name, tt.want, got.Bounds())
+		}
+	}
+	got, err := Crop(img, image.Rect(5, 5, 6, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.RGBAModel.Convert(got.At(15, 25)); c != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("expected the cropped pixel to be white, got %v", c)
+	}
+}
+
+func TestCropCopy(t *testing.T) {
+	// image.Uniform doesn't have sub-images, the pixels are copied instead
+	got, err := Crop(image.NewUniform(color.White), image.Rect(2, 3, 7, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Rect(0, 0, 5, 2); got.Bounds() != want {
+		t.Errorf("expected bounds %v, got %v", want, got.Bounds())
+	}
+}
+
+func TestCropEmpty(t *testing.T) {
+	img := image.NewRGBA(image.Rect(10, 20, 50, 40))
+	for _, r := range []image.Rectangle{
+		image.Rect(40, 0, 50, 10),  // to the right of the image
+		image.Rect(0, 20, 10, 30),  // below the image
+		image.Rect(-10, -10, 0, 0), // above and to the left
+		image.Rect(12, 22, 20, 30), // inside the image bounds, but the rectangle is relative to the top left corner
+	} {
+		if _, err := Crop(img, r); err != ErrEmptyCrop {
+			t.Errorf("%v: expected ErrEmptyCrop, got %v", r, err)
+		}
+	}
+	opts := ScaleOpts{Factor: 1, Crop: image.Rect(100, 100, 110, 110)}
+	if _, err := Cropped(img, opts); err != ErrEmptyCrop {
+		t.Errorf("expected Cropped to return ErrEmptyCrop, got %v", err)
+	}
+	if _, err := toWindow(img, opts); err != ErrEmptyCrop {
+		t.Errorf("expected toWindow to return ErrEmptyCrop, got %v", err)
+	}
+	// Image doesn't crop
+	if got, want := Image(img, opts).Bounds(), image.Rect(0, 0, 40, 20); got != want {
+		t.Errorf("expected Image to ignore the crop area, got bounds %v, expected %v", got, want)
+	}
+}
+
+func TestParseRect(t *testing.T) {
+	r, err := ParseRect("10,20,30,40")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Rect(10, 20, 40, 60); r != want {
+		t.Errorf("expected %v, got %v", want, r)
+	}
+	for _, s := range []string{
+		"",
+		"10,20,30",
+		"10,20,30,",
+		"a,b,c,d",
+		"10 20 30 40",
+		"10,20,0,40",
+		"10,20,30,0",
+		"10,20,-30,40",
+		"10.5,20,30,40",
+	} {
+		if _, err := ParseRect(s); err != ErrInvalidCrop {
+			t.Errorf("%q: expected ErrInvalidCrop, got %v", s, err)
+		}
+	}
+}
+
+func TestScaleOffsetImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(-30, 50, 70, 90))
+	if x, y := getScaledXY(ScaleOpts{Factor: 0.5}, img); x != 50 || y != 20 {
+		t.Errorf("expected 50x20, got %dx%d", x, y)
+	}
+	if x, y := getScaledXY(ScaleOpts{Width: 12, Height: 8}, img); x != 12 || y != 8 {
+		t.Errorf("expected 12x8, got %dx%d", x, y)
+	}
+	tests := []struct {
+		name string
+		opts ScaleOpts
+		want image.Rectangle
+	}{
+		{
+			name: "fits",
+			opts: ScaleOpts{Width: 200, Height: 100, Factor: 1},
+			want: image.Rect(0, 0, 100, 40),
+		},
+		{
+			name: "too wide",
+			opts: ScaleOpts{Width: 50, Height: 100, Factor: 1},
+			want: image.Rect(0, 0, 50, 20),
+		},
+		{
+			name: "too high",
+			opts: ScaleOpts{Width: 200, Height: 10, Factor: 1},
+			want: image.Rect(0, 0, 25, 10),
+		},
+		{
+			name: "cropped",
+			opts: ScaleOpts{Width: 200, Height: 100, Factor: 1, Crop: image.Rect(10, 10, 30, 20)},
+			want: image.Rect(0, 0, 20, 10),
+		},
+	}
+	for _, tt := range tests {
+		got, err := toWindow(img, tt.opts)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if got.Bounds() != tt.want {
+			t.Errorf("%s: expected bounds %v, got %v", tt.name, tt.want, got.Bounds())
+		}
+	}
+}
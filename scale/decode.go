@@ -0,0 +1,64 @@
+package scale
+
+import (
+	"bufio"
+	"image"
+	"io"
+	"os"
+
+	// register the decoders, image.Decode picks the right one based on the content
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// IsSupportedFile pass in the path, and this will return the image format (as registered with the image
+// package: png, jpeg, gif, bmp, tiff, webp) + true if the format is supported - false if not supported.
+// The format is determined by the content of the file, the extension doesn't matter
+func IsSupportedFile(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	_, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", false
+	}
+	return format, true
+}
+
+// decodeFile opens and decodes the image file
+func decodeFile(path string, opts ScaleOpts) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decode(f, opts)
+}
+
+// decode decodes an image in any of the registered formats, returns ErrUnsupportedFileType if the format isn't
+// one of them. JPEGs are rotated and/or flipped as per their EXIF orientation, unless opts.IgnoreOrientation is set
+func decode(r io.Reader, opts ScaleOpts) (image.Image, error) {
+	br := bufio.NewReaderSize(r, exifPeek)
+	// the EXIF data is at the start, so it can be read before decoding. An error here just means the image
+	// is smaller than what we peeked at, or it'll come up again when decoding
+	head, _ := br.Peek(exifPeek)
+	orientation := jpegOrientation(head)
+	img, format, err := image.Decode(br)
+	if err == image.ErrFormat {
+		return nil, ErrUnsupportedFileType
+	}
+	if err != nil {
+		return nil, err
+	}
+	if format == "jpeg" && !opts.IgnoreOrientation {
+		img = orient(img, orientation)
+	}
+	return img, nil
+}
@@ -0,0 +1,127 @@
+package scale
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// exifPeek is how much of the start of a JPEG is checked for the EXIF segment. A segment is at most 64KB,
+	// and the EXIF segment can be preceded by a JFIF segment
+	exifPeek = 128 * 1024
+
+	orientationTag = 0x0112
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG, 1 (normal) if there isn't one, or the data
+// can't be parsed. Only the start of the file is needed, the EXIF data comes before the image data
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+	// the segments are 0xff, a marker, and the length (2 bytes, big endian, including the length itself)
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xd8 || (marker >= 0xd0 && marker <= 0xd7) || marker == 0x01 || marker == 0xff {
+			// markers without a length (and padding)
+			i++
+			continue
+		}
+		if marker == 0xda {
+			// start of the image data, no EXIF segment
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + size
+		if size < 2 || end > len(data) {
+			return 1
+		}
+		if seg := data[i+4 : end]; marker == 0xe1 && bytes.HasPrefix(seg, exifHeader) {
+			return tiffOrientation(seg[len(exifHeader):])
+		}
+		i = end
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of the TIFF structure the EXIF data is stored in
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(t[4:]))
+	if ifd < 8 || ifd+2 > len(t) {
+		return 1
+	}
+	n := int(order.Uint16(t[ifd:]))
+	// each entry is 12 bytes: tag, type, count (4 bytes), and the value (4 bytes, a short is in the first 2)
+	for e := ifd + 2; e+12 <= len(t) && n > 0; e, n = e+12, n-1 {
+		if order.Uint16(t[e:]) != orientationTag {
+			continue
+		}
+		if o := int(order.Uint16(t[e+8:])); o >= 1 && o <= 8 {
+			return o
+		}
+		return 1
+	}
+	return 1
+}
+
+// orient rotates and/or flips the image so it's the right way up, as per the EXIF orientation
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	// copy the image, so the pixels can be moved around directly
+	b := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Rect, src, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// 5 to 8 are rotated by 90 degrees (and flipped), width and height are swapped
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flipped horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180 degrees
+				dx, dy = w-1-x, h-1-y
+			case 4: // flipped vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // needs to be rotated 90 degrees clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // needs to be rotated 90 degrees counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			s, d := rgba.PixOffset(x, y), dst.PixOffset(dx, dy)
+			copy(dst.Pix[d:d+4], rgba.Pix[s:s+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,185 @@
+package scale
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// exifSegment returns an APP1 segment with the orientation in the first IFD, using the given byte order
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	t := &bytes.Buffer{}
+	if order == binary.LittleEndian {
+		t.WriteString("II")
+	} else {
+		t.WriteString("MM")
+	}
+	binary.Write(t, order, uint16(42))
+	binary.Write(t, order, uint32(8)) // the first IFD comes straight after the header
+	binary.Write(t, order, uint16(2)) // number of entries
+	// an entry that isn't the orientation first (ImageWidth, a long), then the orientation (a short)
+	binary.Write(t, order, []uint16{0x0100, 4})
+	binary.Write(t, order, []uint32{1, 640})
+	binary.Write(t, order, []uint16{orientationTag, 3})
+	binary.Write(t, order, uint32(1))
+	binary.Write(t, order, []uint16{orientation, 0})
+	binary.Write(t, order, uint32(0)) // no next IFD
+	return segment(0xe1, append(append([]byte{}, exifHeader...), t.Bytes()...))
+}
+
+// segment returns a JPEG segment: the marker, the length, and the data
+func segment(marker byte, data []byte) []byte {
+	s := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(data)+2))
+	return append(s, data...)
+}
+
+// cornerJPEG returns a 32x16 JPEG, black with a white block in the top left corner, with the segments added
+// after the start of image marker
+func cornerJPEG(t *testing.T, segments ...[]byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			c := color.RGBA{A: 0xff}
+			if x < 8 && y < 8 {
+				c = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, data[2:]...)
+}
+
+// corner returns where the white block ended up: 0 top left, 1 top right, 2 bottom right, 3 bottom left, -1 if
+// it isn't in (just) one of the corners
+func corner(img image.Image) int {
+	b := img.Bounds()
+	found := -1
+	for i, p := range []image.Point{
+		{4, 4},
+		{b.Dx() - 5, 4},
+		{b.Dx() - 5, b.Dy() - 5},
+		{4, b.Dy() - 5},
+	} {
+		if y := color.GrayModel.Convert(img.At(b.Min.X+p.X, b.Min.Y+p.Y)).(color.Gray).Y; y > 0x80 {
+			if found != -1 {
+				return -1
+			}
+			found = i
+		}
+	}
+	return found
+}
+
+func TestOrientation(t *testing.T) {
+	tests := []struct {
+		orientation uint16
+		size        image.Point
+		corner      int
+	}{
+		{orientation: 1, size: image.Pt(32, 16), corner: 0},
+		{orientation: 2, size: image.Pt(32, 16), corner: 1}, // flipped horizontally
+		{orientation: 3, size: image.Pt(32, 16), corner: 2}, // rotated 180 degrees
+		{orientation: 4, size: image.Pt(32, 16), corner: 3}, // flipped vertically
+		{orientation: 5, size: image.Pt(16, 32), corner: 0}, // transposed
+		{orientation: 6, size: image.Pt(16, 32), corner: 1}, // rotated 90 degrees clockwise
+		{orientation: 7, size: image.Pt(16, 32), corner: 2}, // transversed
+		{orientation: 8, size: image.Pt(16, 32), corner: 3}, // rotated 90 degrees counter-clockwise
+		{orientation: 0, size: image.Pt(32, 16), corner: 0}, // invalid, left alone
+		{orientation: 9, size: image.Pt(32, 16), corner: 0},
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		for _, tt := range tests {
+			data := cornerJPEG(t, exifSegment(order, tt.orientation))
+			want := int(tt.orientation)
+			if want < 1 || want > 8 {
+				want = 1
+			}
+			if o := jpegOrientation(data); o != want {
+				t.Errorf("%v, orientation %d: expected to read orientation %d, got %d", order, tt.orientation, want, o)
+			}
+			img, err := decode(bytes.NewReader(data), ScaleOpts{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := img.Bounds().Size(); got != tt.size {
+				t.Errorf("%v, orientation %d: expected size %v, got %v", order, tt.orientation, tt.size, got)
+			} else if c := corner(img); c != tt.corner {
+				t.Errorf("%v, orientation %d: expected the block in corner %d, got %d", order, tt.orientation, tt.corner, c)
+			}
+			// IgnoreOrientation (-noexif) leaves the image as it's stored
+			img, err = decode(bytes.NewReader(data), ScaleOpts{IgnoreOrientation: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := img.Bounds().Size(); got != image.Pt(32, 16) || corner(img) != 0 {
+				t.Errorf("%v, orientation %d: IgnoreOrientation changed the image", order, tt.orientation)
+			}
+		}
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	exif := exifSegment(binary.BigEndian, 6)
+	// the byte order comes after the marker, the length, and the EXIF header
+	badOrder := append([]byte{}, exif...)
+	copy(badOrder[4+len(exifHeader):], "XX")
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{
+			name: "no EXIF",
+			data: cornerJPEG(t),
+			want: 1,
+		},
+		{
+			name: "after a JFIF segment",
+			data: cornerJPEG(t, segment(0xe0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")), exif),
+			want: 6,
+		},
+		{
+			name: "APP1 that isn't EXIF (XMP)",
+			data: cornerJPEG(t, segment(0xe1, []byte("http://ns.adobe.com/xap/1.0/\x00")), exif),
+			want: 6,
+		},
+		{
+			name: "truncated",
+			data: cornerJPEG(t, exif)[:len(exif)-4],
+			want: 1,
+		},
+		{
+			name: "not a JPEG",
+			data: exif,
+			want: 1,
+		},
+		{
+			name: "unknown byte order",
+			data: cornerJPEG(t, badOrder),
+			want: 1,
+		},
+		{
+			name: "IFD offset outside of the segment",
+			data: cornerJPEG(t, segment(0xe1, append(append([]byte{}, exifHeader...), "MM\x00\x2a\x00\x00\xff\xff"...))),
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		if got := jpegOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: expected orientation %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package scale
+
+import (
+	"image"
+	"image/gif"
+	"io"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// Frame is a single frame of an animation, and how long it's shown for
+type Frame struct {
+	Image image.Image
+	Delay time.Duration
+}
+
+// Animation is a decoded animated image, every frame is a complete image (frames are composited already)
+type Animation struct {
+	Frames []Frame
+	// LoopCount as per image/gif: 0 loops forever, -1 shows each frame once, n plays the animation n+1 times
+	LoopCount int
+}
+
+// DecodeGIF decodes all frames of a GIF. GIF frames only contain what changed, and can be smaller than the image,
+// so each frame is drawn over the previous one, honouring the disposal method of the previous frame (leave it,
+// clear it to transparent, or restore what was there before)
+func DecodeGIF(r io.Reader) (*Animation, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		// some encoders don't set the logical screen size, the frames have to fit
+		for _, f := range g.Image {
+			bounds = bounds.Union(f.Bounds())
+		}
+	}
+	canvas := image.NewRGBA(bounds)
+	anim := &Animation{
+		Frames:    make([]Frame, 0, len(g.Image)),
+		LoopCount: g.LoopCount,
+	}
+	for i, f := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var prev *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			prev = copyRGBA(canvas)
+		}
+		draw.Draw(canvas, f.Bounds(), f, f.Bounds().Min, draw.Over)
+		delay := time.Duration(0)
+		if i < len(g.Delay) {
+			// the delay is in 100ths of a second
+			delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		anim.Frames = append(anim.Frames, Frame{
+			Image: copyRGBA(canvas),
+			Delay: delay,
+		})
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, f.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = prev
+		}
+	}
+	return anim, nil
+}
+
+// GIF decodes all frames of a GIF, and crops and scales them (see Cropped)
+func GIF(r io.Reader, opts ScaleOpts) (*Animation, error) {
+	anim, err := DecodeGIF(r)
+	if err != nil {
+		return nil, err
+	}
+	return anim, anim.scale(opts, Cropped)
+}
+
+// GIFToWindow does the same as GIF, only the frames are scaled to fit the window (see FileToWindow)
+func GIFToWindow(r io.Reader, opts ScaleOpts) (*Animation, error) {
+	anim, err := DecodeGIF(r)
+	if err != nil {
+		return nil, err
+	}
+	return anim, anim.scale(opts, toWindow)
+}
+
+// scale replaces each frame with the scaled version
+func (a *Animation) scale(opts ScaleOpts, fn func(image.Image, ScaleOpts) (image.Image, error)) error {
+	for i, f := range a.Frames {
+		img, err := fn(f.Image, opts)
+		if err != nil {
+			return err
+		}
+		a.Frames[i].Image = img
+	}
+	return nil
+}
+
+func copyRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Rect)
+	copy(dst.Pix, src.Pix)
+	return dst
+}
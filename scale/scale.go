@@ -6,11 +6,8 @@ import (
 	"errors"
 	"image"
 	"image/jpeg"
-	"image/png"
+	"io"
 	"math"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"golang.org/x/image/draw"
 )
@@ -23,6 +20,12 @@ type ScaleOpts struct {
 	Width, Height uint
 	Factor        float64
 	Mode          Mode
+	// Crop is the part of the image to keep (see Crop), the zero value keeps the entire image.
+	// The factor, width and height apply to the cropped image. Image ignores it, everything else crops
+	Crop image.Rectangle
+	// IgnoreOrientation leaves JPEGs as they're stored, rather than rotating/flipping them as per the EXIF
+	// orientation (phone photos tend to be stored sideways). Cropping happens after the image is rotated
+	IgnoreOrientation bool
 }
 
 const (
@@ -49,23 +52,9 @@ var (
 		CatmullRomScaling,
 	}
 
-	supportedTypes = map[string]struct{}{
-		"png":  {},
-		"jpeg": {},
-		"jpg":  {},
-	}
-
-	ErrUnsupportedFileType = errors.New("file extension not supported")
+	ErrUnsupportedFileType = errors.New("image format not supported")
 )
 
-// IsSupportedFile pass in the path, and this will return the extension + true if the
-// extension is supported - false if not supported
-func IsSupportedFile(path string) (string, bool) {
-	ext := strings.ToLower(strings.ReplaceAll(filepath.Ext(path), ".", ""))
-	_, ok := supportedTypes[ext]
-	return ext, ok
-}
-
 // Raw again does the same as other functions, but can be used when getting image data directly from
 // a device, such as a webcam stream
 func Raw(frame []byte, opts ScaleOpts) (image.Image, error) {
@@ -73,58 +62,59 @@ func Raw(frame []byte, opts ScaleOpts) (image.Image, error) {
 	if err != nil {
 		return nil, err
 	}
-	scaled := Image(img, opts)
-	return scaled, nil
+	return Cropped(img, opts)
 }
 
 // File does the same thing as Image, but takes a string which should be a valid path to an image file
 // it opens it, scales it, and returns the scaled image
 func File(imgFile string, opts ScaleOpts) (image.Image, error) {
-	inF, err := os.Open(imgFile)
+	src, err := decodeFile(imgFile, opts)
 	if err != nil {
 		return nil, err
 	}
-	ext := strings.ToLower(strings.ReplaceAll(filepath.Ext(imgFile), ".", ""))
-	if _, ok := supportedTypes[ext]; !ok {
-		return nil, ErrUnsupportedFileType
-	}
-	var src image.Image
-	if ext == "png" {
-		src, err = png.Decode(inF)
-	} else {
-		src, err = jpeg.Decode(inF)
-	}
-	// close file, we're done
-	inF.Close()
+	// we have out image, now we can scale it
+	return Cropped(src, opts)
+}
+
+// Reader does the same as File, only the image is read from r (stdin, an HTTP response, a bytes.Reader...)
+func Reader(r io.Reader, opts ScaleOpts) (image.Image, error) {
+	src, err := decode(r, opts)
 	if err != nil {
 		return nil, err
 	}
-	// we have out image, now we can scale it
-	scaled := Image(src, opts)
-	return scaled, nil
+	return Cropped(src, opts)
 }
 
 // FileToWindow does exactly what the File function does, but recalculates the scaling factor based
 // on width and height, which are interpreted as the width/height that can be used to view the image
 func FileToWindow(imgFile string, opts ScaleOpts) (image.Image, error) {
-	inF, err := os.Open(imgFile)
+	src, err := decodeFile(imgFile, opts)
 	if err != nil {
 		return nil, err
 	}
-	ext := strings.ToLower(strings.ReplaceAll(filepath.Ext(imgFile), ".", ""))
-	if _, ok := supportedTypes[ext]; !ok {
-		return nil, ErrUnsupportedFileType
+	return toWindow(src, opts)
+}
+
+// ReaderToWindow does the same as FileToWindow, reading the image from r
+func ReaderToWindow(r io.Reader, opts ScaleOpts) (image.Image, error) {
+	src, err := decode(r, opts)
+	if err != nil {
+		return nil, err
 	}
-	var src image.Image
-	if ext == "png" {
-		src, err = png.Decode(inF)
-	} else {
-		src, err = jpeg.Decode(inF)
+	return toWindow(src, opts)
+}
+
+// toWindow scales the image to fit the window, see FileToWindow
+func toWindow(src image.Image, opts ScaleOpts) (image.Image, error) {
+	// the window has to fit the cropped image
+	src, err := opts.crop(src)
+	if err != nil {
+		return nil, err
 	}
-	inF.Close()
+	opts.Crop = image.Rectangle{}
 	// determine factor
 	if opts.Width != 0 && opts.Height != 0 && opts.Factor != 0 {
-		max := src.Bounds().Max
+		max := src.Bounds().Size()
 		useFact := false
 		if uint(max.Y) > opts.Height {
 			hf := float64(opts.Height) / float64(max.Y)
@@ -149,11 +139,11 @@ func FileToWindow(imgFile string, opts ScaleOpts) (image.Image, error) {
 			opts.Height = 0
 		}
 	}
-	scaled := Image(src, opts)
-	return scaled, nil
+	return Image(src, opts), nil
 }
 
-// Image takes a given image, and returns a scaled version thereof
+// Image takes a given image, and returns a scaled version thereof. The image isn't cropped, opts.Crop is ignored,
+// use Cropped for that
 func Image(src image.Image, opts ScaleOpts) image.Image {
 	x, y := getScaledXY(opts, src)
 	dst := image.NewRGBA(image.Rect(0, 0, x, y))
@@ -170,13 +160,31 @@ func Image(src image.Image, opts ScaleOpts) image.Image {
 	return dst
 }
 
+// Cropped does the same as Image, cropping the image first (see ScaleOpts.Crop). The only error is ErrEmptyCrop,
+// if the crop area doesn't overlap with the image
+func Cropped(src image.Image, opts ScaleOpts) (image.Image, error) {
+	src, err := opts.crop(src)
+	if err != nil {
+		return nil, err
+	}
+	return Image(src, opts), nil
+}
+
+// crop returns the cropped image, or the image itself if there's nothing to crop
+func (o ScaleOpts) crop(src image.Image) (image.Image, error) {
+	if o.Crop.Empty() {
+		return src, nil
+	}
+	return Crop(src, o.Crop)
+}
+
 // scale the current source image accorind to factor, unless width && height are set, then just use those
 func getScaledXY(opts ScaleOpts, src image.Image) (int, int) {
 	if opts.Factor == 0 {
 		return int(opts.Width), int(opts.Height)
 	}
-	max := src.Bounds().Max
-	x, y := math.Round(float64(max.X)*opts.Factor), math.Round(float64(max.Y)*opts.Factor)
+	size := src.Bounds().Size()
+	x, y := math.Round(float64(size.X)*opts.Factor), math.Round(float64(size.Y)*opts.Factor)
 	return int(x), int(y)
 }
 